@@ -0,0 +1,88 @@
+package goffeine
+
+import (
+	"goffeine/internal/utils"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+const (
+	// ringBufferSize is the number of reads a stripe of the read buffer holds, a power of two.
+	ringBufferSize = 16
+	// writeBufferPerCPU sizes the write buffer, as a number of writes per processor.
+	writeBufferPerCPU = 128
+)
+
+// offer results of a readBuffer.
+const (
+	offerSuccess = iota
+	offerFailed
+	offerFull
+)
+
+// A ringBuffer is a bounded lossy buffer that many goroutines offer elements to and a single
+// consumer drains. It is Caffeine's BoundedBuffer.RingBuffer.
+type ringBuffer[E any] struct {
+	head  atomic.Uint64
+	tail  atomic.Uint64
+	slots [ringBufferSize]atomic.Pointer[E]
+	_     [64]byte // keeps the stripes on separate cache lines
+}
+
+// offer adds e unless the buffer is full, or another goroutine won the race for the slot.
+func (b *ringBuffer[E]) offer(e *E) int {
+	head, tail := b.head.Load(), b.tail.Load()
+	if tail-head >= ringBufferSize {
+		return offerFull
+	}
+	if !b.tail.CompareAndSwap(tail, tail+1) {
+		return offerFailed
+	}
+	b.slots[tail&(ringBufferSize-1)].Store(e)
+	return offerSuccess
+}
+
+// drainTo hands the buffered elements over to consumer. It stops at a slot that a producer has
+// claimed but not filled yet, which is picked up by the next drain. The caller is the only
+// consumer.
+func (b *ringBuffer[E]) drainTo(consumer func(e *E)) {
+	head, tail := b.head.Load(), b.tail.Load()
+	for ; head != tail; head++ {
+		slot := &b.slots[head&(ringBufferSize-1)]
+		e := slot.Load()
+		if e == nil {
+			break
+		}
+		slot.Store(nil)
+		consumer(e)
+	}
+	b.head.Store(head)
+}
+
+// A readBuffer records the reads of the cache in ring buffers striped over the processors, so
+// that readers rarely contend. Reads are dropped when a stripe is full, which only costs the
+// policy a little accuracy.
+type readBuffer[E any] struct {
+	stripes []ringBuffer[E]
+}
+
+func newReadBuffer[E any]() *readBuffer[E] {
+	return &readBuffer[E]{stripes: make([]ringBuffer[E], stripes())}
+}
+
+// stripes returns the number of stripes of a buffer, four per processor rounded up to a power of
+// two.
+func stripes() int {
+	return utils.CeilingPowerOfTwo32(4 * runtime.GOMAXPROCS(0))
+}
+
+func (b *readBuffer[E]) offer(e *E) int {
+	return b.stripes[rand.Uint32()&uint32(len(b.stripes)-1)].offer(e)
+}
+
+func (b *readBuffer[E]) drainTo(consumer func(e *E)) {
+	for i := range b.stripes {
+		b.stripes[i].drainTo(consumer)
+	}
+}
@@ -0,0 +1,52 @@
+package goffeine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestRingBufferOfferAndDrain(t *testing.T) {
+	var buffer ringBuffer[int]
+	values := make([]int, ringBufferSize+1)
+	for i := range values {
+		values[i] = i
+	}
+	for i := 0; i < ringBufferSize; i++ {
+		assert.Equal(t, offerSuccess, buffer.offer(&values[i]))
+	}
+	assert.Equal(t, offerFull, buffer.offer(&values[ringBufferSize]))
+
+	var drained []int
+	buffer.drainTo(func(e *int) { drained = append(drained, *e) })
+	assert.Equal(t, values[:ringBufferSize], drained)
+
+	assert.Equal(t, offerSuccess, buffer.offer(&values[ringBufferSize]))
+	drained = nil
+	buffer.drainTo(func(e *int) { drained = append(drained, *e) })
+	assert.Equal(t, []int{ringBufferSize}, drained)
+}
+
+func TestReadBufferIsLossyUnderContention(t *testing.T) {
+	buffer := newReadBuffer[int]()
+	value := 1
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	drained := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10_000; j++ {
+				if buffer.offer(&value) == offerFull && lock.TryLock() {
+					buffer.drainTo(func(*int) { drained++ })
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	buffer.drainTo(func(*int) { drained++ })
+	assert.Greater(t, drained, 0)
+	assert.LessOrEqual(t, drained, 80_000)
+}
@@ -2,23 +2,40 @@ package goffeine
 
 import (
 	"container/list"
+	"goffeine/internal/node"
+	"goffeine/internal/utils"
+	"hash/maphash"
+	"runtime"
 	"sync"
 	"time"
 )
 
-func NewBuilder() *Builder {
-	return &Builder{}
+func NewBuilder[K comparable, V any]() *Builder[K, V] {
+	return &Builder[K, V]{}
 }
 
 // A Builder is used to create a Goffeine instance
-// e.g.	goffeine.NewBuilder().maximumSize(10).ExpireAfterWrite(time.Second, 5).Build()
-type Builder struct {
+// e.g.	goffeine.NewBuilder[string, int]().MaximumSize(10).ExpireAfterWrite(time.Second, 5).Build()
+type Builder[K comparable, V any] struct {
 	maximumSize         int
+	maximumWeight       int
+	weigher             Weigher[K, V]
 	expireMilliseconds  int64
 	refreshMilliseconds int64
+	accessMilliseconds  int64
+	staleMilliseconds   int64
+	expiry              Expiry[K, V]
+	ticker              Ticker
+	loader              CacheLoader[K, V]
+	failureDuration     time.Duration
+	notFoundDuration    time.Duration
+	negativeWeight      int
+	removalListener     RemovalListener[K, V]
+	recordStats         bool
+	adaptiveWindow      bool
 }
 
-func (b *Builder) MaximumSize(size int) *Builder {
+func (b *Builder[K, V]) MaximumSize(size int) *Builder[K, V] {
 	if size < 1 {
 		size = 3 // window: 1, probation: 1, protected: 1
 	}
@@ -26,34 +43,146 @@ func (b *Builder) MaximumSize(size int) *Builder {
 	return b
 }
 
-func (b *Builder) ExpireAfterWrite(duration time.Duration, delay int) *Builder {
+// MaximumWeight bounds the cache by the total weight of its entries instead of their number. The
+// window, probation and protected regions are then sized in weight too. It takes precedence over
+// MaximumSize.
+func (b *Builder[K, V]) MaximumWeight(weight int) *Builder[K, V] {
+	if weight < 1 {
+		weight = 3 // window: 1, probation: 1, protected: 1
+	}
+	b.maximumWeight = weight
+	return b
+}
+
+// Weigher specifies how the entries are weighed against MaximumWeight. Without it every entry
+// weighs 1.
+func (b *Builder[K, V]) Weigher(weigher Weigher[K, V]) *Builder[K, V] {
+	b.weigher = weigher
+	return b
+}
+
+func (b *Builder[K, V]) ExpireAfterWrite(duration time.Duration, delay int) *Builder[K, V] {
 	b.expireMilliseconds = duration.Milliseconds() * int64(delay)
 	return b
 }
 
-func (b *Builder) RefreshAfterWrite(duration time.Duration, delay int) *Builder {
+// ExpireAfterAccess drops an entry once nobody read or wrote it for the given duration. Combined
+// with ExpireAfterWrite the entry goes away at whichever deadline comes first.
+func (b *Builder[K, V]) ExpireAfterAccess(duration time.Duration) *Builder[K, V] {
+	b.accessMilliseconds = duration.Milliseconds()
+	return b
+}
+
+// ExpireAfter lets the expiry decide the lifetime of each entry. It takes precedence over
+// ExpireAfterWrite and ExpireAfterAccess.
+func (b *Builder[K, V]) ExpireAfter(expiry Expiry[K, V]) *Builder[K, V] {
+	b.expiry = expiry
+	return b
+}
+
+func (b *Builder[K, V]) RefreshAfterWrite(duration time.Duration, delay int) *Builder[K, V] {
 	b.refreshMilliseconds = duration.Milliseconds() * int64(delay)
 	return b
 }
 
-func (b *Builder) Build() *Goffeine {
-	windowMaxsize := b.maximumSize / 100
-	if windowMaxsize < 1 {
-		windowMaxsize = 1
+// ServeStale keeps the entries that expired for an extra grace period, during which a read still
+// gets the stale value and starts a background reload with the CacheLoader. While the reload
+// fails, the stale value keeps being served until the grace period ends. GetWithStale tells the
+// stale values apart.
+func (b *Builder[K, V]) ServeStale(grace time.Duration) *Builder[K, V] {
+	b.staleMilliseconds = grace.Milliseconds()
+	return b
+}
+
+// CacheLoader specifies the loader used to reload entries in the background once they are older
+// than RefreshAfterWrite. Until the reload completes, readers keep getting the current value.
+func (b *Builder[K, V]) CacheLoader(loader CacheLoader[K, V]) *Builder[K, V] {
+	b.loader = loader
+	return b
+}
+
+// CacheLoadFailures caches the errors of the loader of a LoadingCache for the given duration, so
+// that a failing dependency is not called again on every Get of the same key. The duration holds
+// whatever the expiration settings of the values are. The ErrNotFound results are only cached with
+// CacheNotFound.
+func (b *Builder[K, V]) CacheLoadFailures(duration time.Duration) *Builder[K, V] {
+	b.failureDuration = duration
+	return b
+}
+
+// CacheNotFound caches the ErrNotFound results of the loader of a LoadingCache for the given
+// duration, so that a lookup of a key that does not exist does not reach the loader again.
+func (b *Builder[K, V]) CacheNotFound(duration time.Duration) *Builder[K, V] {
+	b.notFoundDuration = duration
+	return b
+}
+
+// NegativeWeight specifies the weight of a cached failure or not found result against
+// MaximumWeight, 1 by default. The Weigher is not called for them, as they have no value.
+func (b *Builder[K, V]) NegativeWeight(weight uint32) *Builder[K, V] {
+	b.negativeWeight = int(weight)
+	return b
+}
+
+// RemovalListener specifies a listener that is notified of every value leaving the cache, along
+// with the cause, e.g. to release pooled resources.
+func (b *Builder[K, V]) RemovalListener(listener RemovalListener[K, V]) *Builder[K, V] {
+	b.removalListener = listener
+	return b
+}
+
+// AdaptiveWindow lets the cache move capacity between the window and the main space while it
+// runs. It samples the hit rate and grows the window for recency-skewed workloads, or shrinks it
+// for frequency-skewed ones, instead of keeping it at 1% of the maximum.
+func (b *Builder[K, V]) AdaptiveWindow() *Builder[K, V] {
+	b.adaptiveWindow = true
+	return b
+}
+
+// RecordStats enables the accumulation of the statistics returned by Goffeine.Stats.
+func (b *Builder[K, V]) RecordStats() *Builder[K, V] {
+	b.recordStats = true
+	return b
+}
+
+// Ticker specifies the time source used for expiration, the system clock by default.
+func (b *Builder[K, V]) Ticker(ticker Ticker) *Builder[K, V] {
+	b.ticker = ticker
+	return b
+}
+
+func (b *Builder[K, V]) Build() *Goffeine[K, V] {
+	maximum := b.maximumSize
+	if b.maximumWeight > 0 {
+		maximum = b.maximumWeight
 	}
 
-	probationMaxsize := (b.maximumSize - windowMaxsize) * 20 / 100
-	if probationMaxsize < 1 {
-		probationMaxsize = 1
+	windowMaxsize, probationMaxsize, protectedMaxsize := budgets(maximum)
+
+	ticker := b.ticker
+	if ticker == nil {
+		ticker = SystemTicker{}
 	}
 
-	protectedMaxsize := b.maximumSize - windowMaxsize - probationMaxsize
-	if protectedMaxsize < 1 {
-		protectedMaxsize = 1
+	var climber *hillClimber
+	if b.adaptiveWindow {
+		climber = newHillClimber(maximum)
 	}
 
-	return &Goffeine{
+	negativeWeight := 1
+	if b.negativeWeight > 0 && b.maximumWeight > 0 {
+		negativeWeight = b.negativeWeight
+	}
+
+	var stats *statsCounter
+	if b.recordStats {
+		stats = &statsCounter{}
+	}
+
+	return &Goffeine[K, V]{
 		maximumSize:          b.maximumSize,
+		maximumWeight:        b.maximumWeight,
+		weigher:              b.weigher,
 		windowMaximumSize:    windowMaxsize,
 		window:               list.List{},
 		probationMaximumSize: probationMaxsize,
@@ -62,7 +191,30 @@ func (b *Builder) Build() *Goffeine {
 		protected:            list.List{},
 		expireMilliseconds:   b.expireMilliseconds,
 		refreshMilliseconds:  b.refreshMilliseconds,
+		accessMilliseconds:   b.accessMilliseconds,
+		staleMilliseconds:    b.staleMilliseconds,
+		expiry:               b.expiry,
+		loader:               b.loader,
+		failureMilliseconds:  b.failureDuration.Milliseconds(),
+		notFoundMilliseconds: b.notFoundDuration.Milliseconds(),
+		negativeWeight:       negativeWeight,
+		removalListener:      b.removalListener,
+		stats:                stats,
+		climber:              climber,
 		data:                 &sync.Map{},
-		fsketch:              NewSketch(b.maximumSize),
+		keyLocks:             make([]stripedLock, keyLocksPerCPU*utils.CeilingPowerOfTwo32(runtime.GOMAXPROCS(0))),
+		keySeed:              maphash.MakeSeed(),
+		readBuffer:           newReadBuffer[node.GoffeineNode[K, V]](),
+		writeBuffer:          make(chan func(), writeBufferPerCPU*utils.CeilingPowerOfTwo32(runtime.GOMAXPROCS(0))),
+		fsketch:              NewSketch[K](b.maximumSize),
+		ticker:               ticker,
+		timerWheel:           newTimerWheel[K, V](ticker.Read(), b.staleMilliseconds*int64(time.Millisecond)),
 	}
 }
+
+// BuildLoading creates a LoadingCache that computes missing values with the loader, which is also
+// used to refresh entries after RefreshAfterWrite.
+func (b *Builder[K, V]) BuildLoading(loader CacheLoader[K, V]) *LoadingCache[K, V] {
+	b.loader = loader
+	return &LoadingCache[K, V]{Goffeine: b.Build(), loads: make(map[K]*load[V])}
+}
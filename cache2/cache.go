@@ -1,21 +1,27 @@
 package cache2
 
 import (
-	"goffeine/internal/node"
-	"goffeine/internal/queue"
-	"goffeine/internal/sketch"
+	"goffeine"
+	"goffeine/cache2/internal/node"
+	"goffeine/cache2/internal/queue"
+	"goffeine/cache2/internal/sketch"
 	"math/rand"
 	"sync"
 )
 
+// LocalCache 可以被多个goroutine同时使用：hashmap是sync.Map，其余的状态（各个queue、sketch、Weight
+// 和listener）都由evictionLock保护。删除监听器在释放锁之后才被调用，所以它可以再使用cache。
 type LocalCache struct {
-	maxWeight  int
-	sketch     *sketch.FrequencySketch
-	windowQ    *queue.AccessOrderQueue
-	probationQ *queue.AccessOrderQueue
-	protectedQ *queue.AccessOrderQueue
-	hashmap    sync.Map
-	Weight     int //集合当前权重，容量
+	evictionLock sync.Mutex
+	maxWeight    int
+	sketch       *sketch.FrequencySketch
+	windowQ      *queue.AccessOrderQueue
+	probationQ   *queue.AccessOrderQueue
+	protectedQ   *queue.AccessOrderQueue
+	hashmap      sync.Map
+	Weight       int //集合当前权重，容量
+	listener     func(key string, value interface{}, cause goffeine.RemovalCause)
+	removals     []removal // 锁内删除、还没有通知listener的node
 	//wMaxWeight  int //window大小
 	//ptMaxWeight int // protectedQ size
 }
@@ -40,7 +46,28 @@ func (c *LocalCache) Put(key string, value interface{}, weight int) {
 
 func (c *LocalCache) PutWithWeight(key string, value interface{}, weight int) {
 	pNode := node.NewWithWeight(key, value, weight)
+	c.evictionLock.Lock()
 	c.put(pNode)
+	c.unlock()
+}
+
+// 一次被删除的node，等释放evictionLock之后再通知监听器
+type removal struct {
+	key   string
+	value interface{}
+	cause goffeine.RemovalCause
+}
+
+// 释放evictionLock，然后把锁内删除的node通知给监听器
+func (c *LocalCache) unlock() {
+	removals, listener := c.removals, c.listener
+	c.removals = nil
+	c.evictionLock.Unlock()
+	if listener != nil {
+		for _, r := range removals {
+			listener(r.key, r.value, r.cause)
+		}
+	}
 }
 
 func (c *LocalCache) put(pNewNode *node.Node) {
@@ -57,10 +84,59 @@ func (c *LocalCache) put(pNewNode *node.Node) {
 	}
 }
 
-func (c *LocalCache) remove(queue *queue.AccessOrderQueue, pNode *node.Node) {
+// 设置删除监听器，每个离开cache的node都会通知它，并带上原因
+func (c *LocalCache) SetRemovalListener(listener func(key string, value interface{}, cause goffeine.RemovalCause)) {
+	c.evictionLock.Lock()
+	c.listener = listener
+	c.evictionLock.Unlock()
+}
+
+func (c *LocalCache) remove(queue *queue.AccessOrderQueue, pNode *node.Node, cause goffeine.RemovalCause) {
 	queue.Remove(pNode)
 	c.hashmap.Delete(pNode.Key)
 	c.Weight -= pNode.Weight
+	if c.listener != nil {
+		c.removals = append(c.removals, removal{pNode.Key, pNode.Value, cause})
+	}
+}
+
+// 返回node所在的queue
+func (c *LocalCache) queueOf(pNode *node.Node) *queue.AccessOrderQueue {
+	if pNode.IsInProbation() {
+		return c.probationQ
+	}
+	if pNode.IsInProtected() {
+		return c.protectedQ
+	}
+	return c.windowQ
+}
+
+// 删除key对应的node：从它所在的queue和hashmap里面删除，并减掉它的权重
+func (c *LocalCache) Invalidate(key string) {
+	c.evictionLock.Lock()
+	if v, ok := c.hashmap.Load(key); ok {
+		pNode := v.(*node.Node)
+		c.remove(c.queueOf(pNode), pNode, goffeine.Explicit)
+	}
+	c.unlock()
+}
+
+// 删除所有的node
+func (c *LocalCache) InvalidateAll() {
+	c.InvalidateIf(func(string, interface{}) bool { return true })
+}
+
+// 删除所有满足pred的node
+func (c *LocalCache) InvalidateIf(pred func(key string, value interface{}) bool) {
+	c.evictionLock.Lock()
+	c.hashmap.Range(func(k, v any) bool {
+		pNode := v.(*node.Node)
+		if pred(pNode.Key, pNode.Value) {
+			c.remove(c.queueOf(pNode), pNode, goffeine.Explicit)
+		}
+		return true
+	})
+	c.unlock()
 }
 
 // 从protation queue里面驱逐节点，使整体cache的当前权重收缩到最大权重以内。具体策略：
@@ -74,17 +150,17 @@ func (c *LocalCache) evictFromProbation() {
 		}
 		candidate, ok := c.probationQ.Last()
 		if !ok || victim == candidate { // 到这里没有得到cacidate，但是有victim
-			c.remove(c.probationQ, victim)
+			c.remove(c.probationQ, victim, goffeine.Size)
 			return
 		}
 
 		freqV, freqC := c.sketch.Frequency(victim), c.sketch.Frequency(candidate)
 		if freqC <= 5 {
-			c.remove(c.probationQ, candidate)
+			c.remove(c.probationQ, candidate, goffeine.Size)
 		} else if freqC > freqV {
-			c.remove(c.probationQ, victim)
+			c.remove(c.probationQ, victim, goffeine.Size)
 		} else if rand.Int()&127 == 0 {
-			c.remove(c.probationQ, victim)
+			c.remove(c.probationQ, victim, goffeine.Size)
 		}
 	}
 }
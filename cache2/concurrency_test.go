@@ -0,0 +1,85 @@
+package cache2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"goffeine/cache2/internal/node"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// 多个goroutine同时随机操作cache
+func hammer(goroutines int, op func(random *rand.Rand)) {
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(seed))
+			for j := 0; j < 2_000; j++ {
+				op(random)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+}
+
+// 检查hashmap、各个queue和Weight是一致的
+func assertConsistent(t *testing.T, c *LocalCache) {
+	c.evictionLock.Lock()
+	defer c.evictionLock.Unlock()
+
+	weight := 0
+	c.hashmap.Range(func(k, v any) bool {
+		pNode := v.(*node.Node)
+		assert.Equal(t, k, pNode.Key)
+		assert.True(t, c.queueOf(pNode).Contains(pNode))
+		weight += pNode.Weight
+		return true
+	})
+	assert.Equal(t, weight, c.Weight)
+	assert.Equal(t, weight, c.windowQ.Weight()+c.probationQ.Weight()+c.protectedQ.Weight())
+	assert.LessOrEqual(t, c.windowQ.Weight(), c.windowQ.MaxWeight)
+}
+
+func TestConcurrentPutAndInvalidate(t *testing.T) {
+	c := NewLocalCache(200, 20, 120)
+	var puts, removals atomic.Int64
+	c.SetRemovalListener(func(key string, value interface{}, cause goffeine.RemovalCause) { removals.Add(1) })
+	hammer(8, func(random *rand.Rand) {
+		key := strconv.Itoa(random.Intn(300))
+		switch random.Intn(500) {
+		case 0, 1, 2, 3, 4, 5, 6, 7, 8, 9:
+			c.Invalidate(key)
+		case 10:
+			c.InvalidateIf(func(k string, _ interface{}) bool { return k == key })
+		default:
+			c.PutWithWeight(key, key, 1+random.Intn(10))
+			puts.Add(1)
+		}
+	})
+	assertConsistent(t, &c)
+
+	c.InvalidateAll()
+	assertConsistent(t, &c)
+	assert.Equal(t, 0, c.Weight)
+	assert.LessOrEqual(t, removals.Load(), puts.Load())
+}
+
+func TestRemovalListenerMayUseTheCache(t *testing.T) {
+	c := NewLocalCache(100, 10, 60)
+	c.SetRemovalListener(func(key string, value interface{}, cause goffeine.RemovalCause) {
+		if cause == goffeine.Size && !strings.HasSuffix(key, "-removed") {
+			c.Invalidate(key + "0")
+			c.PutWithWeight(key+"-removed", value, 1)
+		}
+	})
+	hammer(4, func(random *rand.Rand) {
+		c.PutWithWeight(strconv.Itoa(random.Intn(1000)), 1, 1+random.Intn(5))
+	})
+	assertConsistent(t, &c)
+}
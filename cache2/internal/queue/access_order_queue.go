@@ -131,7 +131,7 @@ func (q *AccessOrderQueue) UnlinkFirst() (*node.Node, bool) {
 	}
 	pElement := q.queue.Front()
 	v := q.queue.Remove(pElement)
-	q.data.Delete(pElement.Value)
+	q.data.Delete(v.(*node.Node).Key)
 	return v.(*node.Node), true
 }
 
@@ -144,7 +144,7 @@ func (q *AccessOrderQueue) UnlinkLast() (*node.Node, bool) {
 	}
 	pElement := q.queue.Back()
 	v := q.queue.Remove(pElement)
-	q.data.Delete(pElement.Value)
+	q.data.Delete(v.(*node.Node).Key)
 	return v.(*node.Node), true
 }
 
@@ -2,7 +2,9 @@ package cache2
 
 import (
 	"github.com/stretchr/testify/assert"
-	"goffeine/internal/node"
+	"goffeine"
+	"goffeine/cache2/internal/node"
+	"strconv"
 	"testing"
 )
 
@@ -120,3 +122,64 @@ func TestEvictFromProbationShouldDoNothingWhenWindowQueueWeightLessThanMaxWeight
 	cache.evictFromProbation() // 这里不会淘汰任何node
 	assert.Equal(50, cache.probationQ.Weight())
 }
+
+func TestInvalidate(t *testing.T) {
+	assert := assert.New(t)
+	cache := newLocalCache(100, 20, 60)
+	cache.PutWithWeight("key_1", 1, 10)
+	cache.PutWithWeight("key_2", 2, 15)
+	cache.PutWithWeight("key_3", 3, 10) // key_1, key_2 到 probation
+	assert.Equal(35, cache.Weight)
+
+	cache.Invalidate("key_1") // probation
+	cache.Invalidate("key_3") // window
+	cache.Invalidate("key_4")
+	assert.Equal(15, cache.Weight)
+	_, ok := cache.hashmap.Load("key_1")
+	assert.Equal(false, ok)
+	assert.Equal(15, cache.probationQ.Weight())
+	assert.Equal(0, cache.windowQ.Weight())
+
+	cache.PutWithWeight("key_3", 3, 5) // 删除后可以重新放入
+	assert.Equal(20, cache.Weight)
+	assert.Equal(5, cache.windowQ.Weight())
+}
+
+func TestInvalidateIfAndInvalidateAll(t *testing.T) {
+	assert := assert.New(t)
+	cache := newLocalCache(100, 20, 60)
+	for i := 1; i <= 5; i++ {
+		cache.PutWithWeight("key_"+strconv.Itoa(i), i, 5)
+	}
+	cache.InvalidateIf(func(key string, value interface{}) bool { return value.(int)%2 == 0 })
+	assert.Equal(15, cache.Weight)
+	_, ok := cache.hashmap.Load("key_2")
+	assert.Equal(false, ok)
+
+	cache.InvalidateAll()
+	assert.Equal(0, cache.Weight)
+	assert.Equal(0, cache.windowQ.Weight())
+	assert.Equal(0, cache.probationQ.Weight())
+}
+
+func TestRemovalListener(t *testing.T) {
+	assert := assert.New(t)
+	cache := newLocalCache(100, 20, 60)
+	var causes []goffeine.RemovalCause
+	var keys []string
+	cache.SetRemovalListener(func(key string, value interface{}, cause goffeine.RemovalCause) {
+		keys = append(keys, key)
+		causes = append(causes, cause)
+	})
+	cache.putToWindowQueue(node.NewWithWeight("key_1", 1, 10))
+	cache.putToWindowQueue(node.NewWithWeight("key_2", 2, 80))
+	cache.putToWindowQueue(node.NewWithWeight("key_3", 3, 10))
+	cache.putToWindowQueue(node.NewWithWeight("key_4", 4, 15))
+	cache.evictFromWindow()
+	cache.evictFromProbation() // 淘汰key_3, key_1
+	cache.PutWithWeight("key_5", 5, 5)
+	cache.Invalidate("key_5")
+
+	assert.Equal([]string{"key_3", "key_1", "key_5"}, keys)
+	assert.Equal([]goffeine.RemovalCause{goffeine.Size, goffeine.Size, goffeine.Explicit}, causes)
+}
@@ -0,0 +1,151 @@
+package goffeine
+
+import (
+	"goffeine/internal/node"
+	"math"
+)
+
+const (
+	// hillClimberRestartThreshold is the change in hit rate that restarts the climber with a full
+	// step, e.g. when the workload shifts.
+	hillClimberRestartThreshold = 0.05
+	// hillClimberStepPercent is the share of the maximum a full step moves.
+	hillClimberStepPercent = 0.0625
+	// hillClimberStepDecayRate shrinks the step while the hit rate is stable, so the climber
+	// converges.
+	hillClimberStepDecayRate = 0.98
+	// queueTransferThreshold bounds the entries moved between regions by a single adjustment.
+	queueTransferThreshold = 1_000
+)
+
+// A hillClimber samples the hit rate of the cache and decides how much capacity moves between
+// the window and the main space, as Caffeine does. A nil hillClimber records nothing.
+type hillClimber struct {
+	hitsInSample          int
+	missesInSample        int
+	previousSampleHitRate float64
+	stepSize              float64
+	adjustment            int
+}
+
+func newHillClimber(maximum int) *hillClimber {
+	c := &hillClimber{}
+	c.reset(maximum)
+	return c
+}
+
+// reset starts a new sample for a cache bounded by maximum. The first step shrinks the window.
+func (c *hillClimber) reset(maximum int) {
+	if c == nil {
+		return
+	}
+	c.hitsInSample = 0
+	c.missesInSample = 0
+	c.stepSize = -hillClimberStepPercent * float64(maximum)
+}
+
+func (c *hillClimber) recordHit() {
+	if c != nil {
+		c.hitsInSample++
+	}
+}
+
+func (c *hillClimber) recordMiss() {
+	if c != nil {
+		c.missesInSample++
+	}
+}
+
+// determineAdjustment sets the adjustment once the sample holds sampleSize requests. The climber
+// keeps going the same way while the hit rate improves and turns back when it degrades.
+func (c *hillClimber) determineAdjustment(sampleSize int, maximum int) {
+	requestCount := c.hitsInSample + c.missesInSample
+	if requestCount < sampleSize {
+		return
+	}
+
+	hitRate := float64(c.hitsInSample) / float64(requestCount)
+	hitRateChange := hitRate - c.previousSampleHitRate
+	amount := -c.stepSize
+	if hitRateChange >= 0 {
+		amount = c.stepSize
+	}
+	if math.Abs(hitRateChange) >= hillClimberRestartThreshold {
+		c.stepSize = math.Copysign(hillClimberStepPercent*float64(maximum), amount)
+	} else {
+		c.stepSize = hillClimberStepDecayRate * amount
+	}
+	c.previousSampleHitRate = hitRate
+	c.adjustment = int(amount)
+	c.hitsInSample = 0
+	c.missesInSample = 0
+}
+
+// climb moves capacity between the window and protected as the hill climber decides. It does
+// nothing unless the cache was built with AdaptiveWindow.
+func (g *Goffeine[K, V]) climb() {
+	if g.climber == nil {
+		return
+	}
+	g.climber.adjustment = 0
+	g.climber.determineAdjustment(g.fsketch.SampleSize, g.maximum())
+	g.demoteFromProtected()
+	switch amount := g.climber.adjustment; {
+	case amount > 0:
+		g.increaseWindow(amount)
+	case amount < 0:
+		g.decreaseWindow(-amount)
+	}
+}
+
+// increaseWindow moves up to amount of capacity from protected to the window, along with the
+// least recently used entries of the main space that fit in it.
+func (g *Goffeine[K, V]) increaseWindow(amount int) {
+	if g.protectedMaximumSize <= 1 {
+		return
+	}
+	quota := min(amount, g.protectedMaximumSize-1)
+	g.protectedMaximumSize -= quota
+	g.windowMaximumSize += quota
+	g.demoteFromProtected()
+
+	for i := 0; i < queueTransferThreshold; i++ {
+		candidate := g.probation.Back()
+		if candidate == nil || quota < weightOf[K, V](candidate) {
+			candidate = g.protected.Back()
+		}
+		if candidate == nil || quota < weightOf[K, V](candidate) {
+			break
+		}
+		quota -= weightOf[K, V](candidate)
+		g.linkFront(g.unlink(nodeOf[K, V](candidate)), node.WindowPosition)
+	}
+
+	// give back what could not be filled
+	g.protectedMaximumSize += quota
+	g.windowMaximumSize -= quota
+}
+
+// decreaseWindow moves up to amount of capacity from the window to protected, along with the
+// window's least recently used entries that fit in it, which go to probation.
+func (g *Goffeine[K, V]) decreaseWindow(amount int) {
+	if g.windowMaximumSize <= 1 {
+		return
+	}
+	quota := min(amount, g.windowMaximumSize-1)
+	g.protectedMaximumSize += quota
+	g.windowMaximumSize -= quota
+
+	for i := 0; i < queueTransferThreshold; i++ {
+		candidate := g.window.Back()
+		if candidate == nil || quota < weightOf[K, V](candidate) {
+			break
+		}
+		quota -= weightOf[K, V](candidate)
+		g.linkFront(g.unlink(nodeOf[K, V](candidate)), node.ProbationPosition)
+	}
+
+	// give back what could not be filled
+	g.protectedMaximumSize -= quota
+	g.windowMaximumSize += quota
+}
@@ -0,0 +1,118 @@
+package goffeine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+// fill puts maximum entries of weight 1 in cache, so the climber has entries to move.
+func fill(cache *Goffeine[int, int], maximum int) {
+	for i := 0; i < maximum; i++ {
+		cache.Put(i, i)
+	}
+	cache.CleanUp()
+}
+
+// climbSamples lets the climber adjust the window after each of n samples, whose hit rate is
+// hitRate of the window's maximum at the time. The samples are fixed, unlike those of a
+// workload, which depend on the sketch's seed and on the admission's jitter.
+func climbSamples[K comparable, V any](g *Goffeine[K, V], n int, hitRate func(window int) float64) {
+	g.evictionLock.Lock()
+	defer g.evictionLock.Unlock()
+	for i := 0; i < n; i++ {
+		sampleSize := g.fsketch.SampleSize
+		hits := int(hitRate(g.windowMaximumSize) * float64(sampleSize))
+		g.climber.hitsInSample, g.climber.missesInSample = hits, sampleSize-hits
+		g.climb()
+	}
+}
+
+// peakAt returns a hit rate that is highest for a window of best, of a cache bounded by maximum.
+func peakAt(best int, maximum int) func(window int) float64 {
+	return func(window int) float64 {
+		return 1 - math.Abs(float64(window-best))/float64(maximum)
+	}
+}
+
+func assertBudgets[K comparable, V any](t *testing.T, g *Goffeine[K, V], maximum int) {
+	assert.Equal(t, maximum, g.windowMaximumSize+g.probationMaximumSize+g.protectedMaximumSize)
+	assert.GreaterOrEqual(t, g.windowMaximumSize, 1)
+	assert.GreaterOrEqual(t, g.protectedMaximumSize, 1)
+	assert.LessOrEqual(t, g.WeightedSize(), maximum)
+	assertRegionWeights(t, g)
+}
+
+func TestClimberGrowsTheWindowForRecency(t *testing.T) {
+	cache := NewBuilder[int, int]().MaximumSize(1000).AdaptiveWindow().Build()
+	fill(cache, 1000)
+	climbSamples(cache, 100, peakAt(800, 1000))
+
+	assert.InDelta(t, 800, cache.windowMaximumSize, 100)
+	assertBudgets(t, cache, 1000)
+}
+
+func TestClimberShrinksTheWindowForFrequency(t *testing.T) {
+	cache := NewBuilder[int, int]().MaximumSize(1000).AdaptiveWindow().Build()
+	fill(cache, 1000)
+	climbSamples(cache, 100, peakAt(800, 1000))
+	grown := cache.windowMaximumSize
+
+	climbSamples(cache, 100, peakAt(50, 1000))
+	assert.Less(t, cache.windowMaximumSize, grown)
+	assert.InDelta(t, 50, cache.windowMaximumSize, 100)
+	assertBudgets(t, cache, 1000)
+}
+
+func TestClimberWithWeights(t *testing.T) {
+	cache := NewBuilder[int, int]().MaximumWeight(5000).AdaptiveWindow().
+		Weigher(func(key int, value int) uint32 { return uint32(key%10 + 1) }).Build()
+	for i := 0; i < 5000; i++ {
+		cache.Put(i, i)
+	}
+	cache.CleanUp()
+	climbSamples(cache, 100, peakAt(4000, 5000))
+
+	assert.InDelta(t, 4000, cache.windowMaximumSize, 500)
+	assertBudgets(t, cache, 5000)
+}
+
+func TestDetermineAdjustment(t *testing.T) {
+	climber := newHillClimber(1000)
+	climber.recordHit()
+	climber.determineAdjustment(10, 1000)
+	assert.Equal(t, 0, climber.adjustment, "waits for a full sample")
+
+	for i := 0; i < 9; i++ {
+		climber.recordMiss()
+	}
+	climber.determineAdjustment(10, 1000)
+	assert.Equal(t, -62, climber.adjustment, "the first step shrinks the window")
+	assert.Equal(t, -62.5, climber.stepSize, "a hit rate change of 0.1 restarts with a full step")
+	assert.Equal(t, 0, climber.hitsInSample+climber.missesInSample)
+
+	for i := 0; i < 10; i++ {
+		climber.recordMiss()
+	}
+	climber.determineAdjustment(10, 1000)
+	assert.Equal(t, 62, climber.adjustment, "a worse hit rate turns back")
+	assert.Equal(t, 62.5, climber.stepSize)
+
+	for i := 0; i < 10; i++ {
+		climber.recordMiss()
+	}
+	climber.determineAdjustment(10, 1000)
+	assert.Equal(t, 62, climber.adjustment, "a stable hit rate keeps going")
+	assert.Equal(t, 62.5*hillClimberStepDecayRate, climber.stepSize, "and decays the step")
+}
+
+func TestSetMaximumResetsTheClimber(t *testing.T) {
+	cache := NewBuilder[int, int]().MaximumSize(1000).AdaptiveWindow().Build()
+	fill(cache, 1000)
+	climbSamples(cache, 100, peakAt(800, 1000))
+	cache.Policy().SetMaximum(2000)
+
+	assert.Equal(t, 20, cache.windowMaximumSize)
+	assert.Equal(t, -125.0, cache.climber.stepSize)
+	assertBudgets(t, cache, 2000)
+}
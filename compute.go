@@ -0,0 +1,105 @@
+package goffeine
+
+import "goffeine/internal/node"
+
+// keyLocksPerCPU sizes the striped key locks, as a number of stripes per processor.
+const keyLocksPerCPU = 64
+
+// Compute atomically computes the entry of key from its current value, if it is present. The
+// remapping returns the new value, or false to remove the entry. It runs under the key lock, so the
+// other writes of key wait for it. It must be quick, and must neither write nor load any key of
+// the cache: the keys share striped locks, so even another key may deadlock it. Compute returns
+// the new value and whether there is one.
+//
+// e.g.	cache.Compute(key, func(key string, count int, present bool) (int, bool) { return count + 1, true })
+func (g *Goffeine[K, V]) Compute(key K, remapping func(key K, oldValue V, present bool) (V, bool)) (V, bool) {
+	return g.compute(key, true, true, func(oldValue V, present bool) (V, bool) {
+		return remapping(key, oldValue, present)
+	})
+}
+
+// ComputeIfAbsent returns the value of key, or atomically computes it with mapping if it is
+// absent. The mapping returns false to leave the entry absent. Like Compute, it holds the key lock
+// while mapping runs.
+func (g *Goffeine[K, V]) ComputeIfAbsent(key K, mapping func(key K) (V, bool)) (V, bool) {
+	if value, ok := g.getIfPresent(key); ok {
+		return value, true
+	}
+	return g.compute(key, true, false, func(V, bool) (V, bool) { return mapping(key) })
+}
+
+// ComputeIfPresent atomically computes the entry of key from its current value, if it is present.
+// The remapping returns false to remove the entry. Like Compute, it holds the key lock while
+// remapping runs.
+func (g *Goffeine[K, V]) ComputeIfPresent(key K, remapping func(key K, oldValue V) (V, bool)) (V, bool) {
+	return g.compute(key, false, true, func(oldValue V, _ bool) (V, bool) { return remapping(key, oldValue) })
+}
+
+// Merge puts value if key is absent, and otherwise atomically combines it with the current value.
+// The remapping returns false to remove the entry. Like Compute, it holds the key lock while
+// remapping runs.
+//
+// e.g.	cache.Merge(key, []string{event}, func(events, event []string) ([]string, bool) { return append(events, event...), true })
+func (g *Goffeine[K, V]) Merge(key K, value V, remapping func(oldValue V, value V) (V, bool)) (V, bool) {
+	return g.compute(key, true, true, func(oldValue V, present bool) (V, bool) {
+		if !present {
+			return value, true
+		}
+		return remapping(oldValue, value)
+	})
+}
+
+// compute applies remapping to the entry of key under the key lock. An expired entry or a cached
+// failure is absent. remapping is only called if the entry is absent and ifAbsent is set, or if it
+// is present and ifPresent is set; the entry is left alone otherwise.
+func (g *Goffeine[K, V]) compute(key K, ifAbsent bool, ifPresent bool, remapping func(oldValue V, present bool) (V, bool)) (V, bool) {
+	value, ok, task, r := g.remap(key, ifAbsent, ifPresent, remapping)
+	g.deliver(r)
+	if task != nil {
+		g.bufferWrite(task)
+		g.scheduleDrainBuffers()
+	}
+	return value, ok
+}
+
+// remap is the part of compute under the key lock. It returns the value of key afterwards, and the
+// write task and the removal of the write or removal it made, if any, like write.
+func (g *Goffeine[K, V]) remap(key K, ifAbsent bool, ifPresent bool, remapping func(oldValue V, present bool) (V, bool)) (V, bool, func(), *removal[K, V]) {
+	lock := g.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+	var gnode *node.GoffeineNode[K, V]
+	var oldValue V
+	present, expired := false, false
+	if v, ok := g.data.Load(key); ok {
+		gnode = v.(*node.GoffeineNode[K, V])
+		value, err := gnode.Load()
+		expired = err == nil && gnode.IsExpired(g.ticker.Read())
+		if err == nil && !expired {
+			oldValue, present = value, true
+		}
+	}
+	if (present && !ifPresent) || (!present && !ifAbsent) {
+		return oldValue, present, nil, nil
+	}
+
+	value, ok := remapping(oldValue, present)
+	var task func()
+	var r *removal[K, V]
+	if ok {
+		task, r = g.write(key, value, nil, g.ticker.Read(), func(gnode *node.GoffeineNode[K, V], now int64, created bool) {
+			// a value replacing an expired one is as good as created
+			g.expireAfterWrite(gnode, now, created || !present)
+		})
+	} else if gnode != nil {
+		task, r = g.remove(gnode, Explicit)
+	}
+	if r != nil && expired {
+		r.cause = Expired
+	}
+	if !ok {
+		var zero V
+		return zero, false, task, r
+	}
+	return value, true, task, r
+}
@@ -0,0 +1,134 @@
+package goffeine_test
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"sync"
+	"testing"
+	"time"
+)
+
+func increment(_ string, count int, _ bool) (int, bool) { return count + 1, true }
+
+func TestCompute(t *testing.T) {
+	var removed []string
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).
+		RemovalListener(func(key string, value int, cause goffeine.RemovalCause) {
+			removed = append(removed, fmt.Sprintf("%s=%d %s", key, value, cause))
+		}).Build()
+
+	v, ok := cache.Compute("count", increment)
+	assert.Equal(t, []any{1, true}, []any{v, ok})
+	v, ok = cache.Compute("count", increment)
+	assert.Equal(t, []any{2, true}, []any{v, ok})
+	v, _ = cache.Get("count")
+	assert.Equal(t, 2, v)
+
+	// a false result removes the entry
+	v, ok = cache.Compute("count", func(key string, count int, present bool) (int, bool) {
+		assert.True(t, present)
+		return 0, false
+	})
+	assert.Equal(t, []any{0, false}, []any{v, ok})
+	_, ok = cache.Get("count")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"count=1 Replaced", "count=2 Explicit"}, removed)
+}
+
+func TestComputeIfAbsentAndIfPresent(t *testing.T) {
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).Build()
+
+	v, ok := cache.ComputeIfPresent("key", func(string, int) (int, bool) {
+		assert.Fail(t, "called for an absent key")
+		return 0, true
+	})
+	assert.Equal(t, []any{0, false}, []any{v, ok})
+	assert.Equal(t, 0, cache.EstimatedSize())
+
+	v, ok = cache.ComputeIfAbsent("key", func(string) (int, bool) { return 1, true })
+	assert.Equal(t, []any{1, true}, []any{v, ok})
+	v, ok = cache.ComputeIfAbsent("key", func(string) (int, bool) {
+		assert.Fail(t, "called for a present key")
+		return 2, true
+	})
+	assert.Equal(t, []any{1, true}, []any{v, ok})
+
+	v, ok = cache.ComputeIfPresent("key", func(_ string, v int) (int, bool) { return v * 10, true })
+	assert.Equal(t, []any{10, true}, []any{v, ok})
+	_, ok = cache.ComputeIfPresent("key", func(string, int) (int, bool) { return 0, false })
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.EstimatedSize())
+}
+
+func TestComputeTreatsExpiredEntriesAsAbsent(t *testing.T) {
+	ticker := &FakeTicker{}
+	var causes []goffeine.RemovalCause
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Second, 1).
+		RemovalListener(func(key string, value int, cause goffeine.RemovalCause) { causes = append(causes, cause) }).
+		Ticker(ticker).Build()
+
+	cache.Put("count", 41)
+	ticker.Advance(2 * time.Second)
+	v, ok := cache.Compute("count", func(_ string, count int, present bool) (int, bool) {
+		assert.False(t, present)
+		assert.Equal(t, 0, count)
+		return 1, true
+	})
+	assert.Equal(t, []any{1, true}, []any{v, ok})
+	assert.Equal(t, []goffeine.RemovalCause{goffeine.Expired}, causes)
+
+	// the computed entry gets a fresh deadline
+	ticker.Advance(500 * time.Millisecond)
+	v, ok = cache.Get("count")
+	assert.Equal(t, []any{1, true}, []any{v, ok})
+}
+
+func TestMergeKeepsTheWeightsAccurate(t *testing.T) {
+	cache := goffeine.NewBuilder[string, []int]().MaximumWeight(10_000).
+		Weigher(func(key string, values []int) uint32 { return uint32(len(values)) }).Build()
+	appendAll := func(values []int, value []int) ([]int, bool) {
+		return append(append([]int(nil), values...), value...), true
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				cache.Merge(fmt.Sprint("key", j%4), []int{j}, appendAll)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for j := 0; j < 4; j++ {
+		values, ok := cache.Get(fmt.Sprint("key", j))
+		assert.True(t, ok)
+		total += len(values)
+	}
+	assert.Equal(t, 8*500, total, "no merge was lost")
+	assert.Equal(t, total, cache.WeightedSize())
+}
+
+func TestComputeIsAtomicWithPut(t *testing.T) {
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).Build()
+	cache.Put("count", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				cache.Compute("count", increment)
+				cache.Put("other", j)
+			}
+		}()
+	}
+	wg.Wait()
+	v, _ := cache.Get("count")
+	assert.Equal(t, 8000, v)
+}
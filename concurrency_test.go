@@ -0,0 +1,176 @@
+package goffeine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// assertConsistent checks, once the cache is quiet, that the policy agrees with data.
+func assertConsistent[K comparable, V any](t *testing.T, g *Goffeine[K, V]) {
+	g.CleanUp()
+	g.evictionLock.Lock()
+	defer g.evictionLock.Unlock()
+
+	mapped := 0
+	g.data.Range(func(_, v any) bool {
+		mapped++
+		return true
+	})
+	linked := g.window.Len() + g.probation.Len() + g.protected.Len()
+	assert.Equal(t, mapped, linked)
+	assert.Equal(t, mapped, g.EstimatedSize())
+	assert.LessOrEqual(t, g.weightedSize(), g.maximum())
+	assertRegionWeights(t, g)
+	for _, region := range []struct{ weight, maximum int }{
+		{g.windowWeight, g.windowMaximumSize}, {g.protectedWeight, g.protectedMaximumSize},
+	} {
+		assert.LessOrEqual(t, region.weight, region.maximum)
+	}
+}
+
+// hammer runs op from several goroutines, each with its own random source.
+func hammer(goroutines int, op func(random *rand.Rand)) {
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(seed))
+			for j := 0; j < 20_000; j++ {
+				op(random)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+}
+
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	var removals atomic.Int64
+	cache := NewBuilder[int, int]().MaximumSize(500).RecordStats().
+		RemovalListener(func(key int, value int, cause RemovalCause) { removals.Add(1) }).Build()
+	var puts atomic.Int64
+	hammer(8, func(random *rand.Rand) {
+		key := random.Intn(2000)
+		switch random.Intn(10) {
+		case 0:
+			cache.Invalidate(key)
+		case 1, 2:
+			cache.Put(key, key)
+			puts.Add(1)
+		case 3:
+			cache.GetAll([]int{key, key + 1, key + 2})
+		default:
+			if v, ok := cache.Get(key); ok {
+				assert.Equal(t, key, v)
+			}
+		}
+	})
+
+	assertConsistent(t, cache)
+	// every put either replaced an entry, was removed, or is still there
+	assert.Equal(t, puts.Load(), removals.Load()+int64(cache.EstimatedSize()))
+}
+
+func TestConcurrentWeightedWrites(t *testing.T) {
+	cache := NewBuilder[int, string]().MaximumWeight(5000).AdaptiveWindow().
+		Weigher(func(key int, value string) uint32 { return uint32(len(value)) }).Build()
+	hammer(8, func(random *rand.Rand) {
+		key := random.Intn(1000)
+		if random.Intn(3) == 0 {
+			cache.Put(key, strings.Repeat("x", random.Intn(50)))
+		} else {
+			cache.Get(key)
+		}
+	})
+	assertConsistent(t, cache)
+}
+
+func TestConcurrentComputes(t *testing.T) {
+	cache := NewBuilder[int, string]().MaximumWeight(5000).
+		Weigher(func(key int, value string) uint32 { return uint32(len(value)) }).Build()
+	hammer(8, func(random *rand.Rand) {
+		key := random.Intn(1000)
+		switch random.Intn(6) {
+		case 0:
+			cache.Invalidate(key)
+		case 1:
+			cache.Put(key, "x")
+		case 2:
+			cache.Merge(key, "x", func(value, x string) (string, bool) { return value + x, len(value) < 40 })
+		case 3:
+			cache.ComputeIfPresent(key, func(_ int, value string) (string, bool) { return value[1:], len(value) > 1 })
+		default:
+			cache.Get(key)
+		}
+	})
+	assertConsistent(t, cache)
+}
+
+func TestConcurrentExpiration(t *testing.T) {
+	var nanos atomic.Int64
+	ticker := tickerFunc(func() int64 { return nanos.Add(int64(time.Millisecond)) })
+	cache := NewBuilder[int, int]().MaximumSize(500).ExpireAfterAccess(time.Second).Ticker(ticker).Build()
+	hammer(8, func(random *rand.Rand) {
+		key := random.Intn(1000)
+		switch random.Intn(4) {
+		case 0:
+			cache.PutWithDelay(key, key, int64(random.Intn(2000)))
+		case 1:
+			cache.Invalidate(key)
+		default:
+			cache.Get(key)
+		}
+	})
+	assertConsistent(t, cache)
+}
+
+func TestRemovalListenerMayUseTheCache(t *testing.T) {
+	var cache *Goffeine[int, int]
+	cache = NewBuilder[int, int]().MaximumSize(10).
+		RemovalListener(func(key int, value int, cause RemovalCause) {
+			if cause == Size {
+				cache.Get(key + 1)
+				cache.Invalidate(key + 2)
+			}
+		}).Build()
+	for i := 0; i < 1000; i++ {
+		cache.Put(i, i)
+	}
+	assertConsistent(t, cache)
+}
+
+func TestRemovalListenerMayLoad(t *testing.T) {
+	var cache *LoadingCache[int, int]
+	cache = NewBuilder[int, int]().MaximumSize(10).
+		RemovalListener(func(key int, value int, cause RemovalCause) {
+			if cause == Size && key < 1000 {
+				cache.Get(key + 1000)
+			}
+		}).BuildLoading(LoaderFunc[int, int](func(key int) (int, error) { return key, nil }))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// the lookup that deadlocked only drains when the key was loaded meanwhile, which is rare
+		for round := 0; round < 2; round++ {
+			hammer(8, func(random *rand.Rand) {
+				cache.Get(random.Intn(30))
+			})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		t.Fatal("deadlocked")
+	}
+	assertConsistent(t, cache.Goffeine)
+}
+
+type tickerFunc func() int64
+
+func (f tickerFunc) Read() int64 { return f() }
@@ -0,0 +1,284 @@
+package goffeine
+
+import (
+	"container/list"
+	"goffeine/internal/node"
+	"math/rand"
+)
+
+// admitHashDosThreshold is the candidate frequency above which a losing candidate is still
+// admitted at random, so an attacker can not pin a victim by flooding its counters.
+const admitHashDosThreshold = 6
+
+// A Weigher calculates the weight of an entry, e.g. the size of a blob in bytes. The weight is
+// calculated when the entry is written and does not change until the next write. An entry that
+// weighs 0 is never evicted because of size.
+type Weigher[K comparable, V any] func(key K, value V) uint32
+
+// weigh returns the weight of an entry, 1 when the cache has no Weigher.
+func (g *Goffeine[K, V]) weigh(key K, value V) int {
+	if g.weigher == nil {
+		return 1
+	}
+	return int(g.weigher(key, value))
+}
+
+// maximum returns the bound of the cache, in weight when it is bounded by MaximumWeight.
+func (g *Goffeine[K, V]) maximum() int {
+	if g.maximumWeight > 0 {
+		return g.maximumWeight
+	}
+	return g.windowMaximumSize + g.probationMaximumSize + g.protectedMaximumSize
+}
+
+// nodeOf returns the node held by an element of a region.
+func nodeOf[K comparable, V any](ele *list.Element) *node.GoffeineNode[K, V] {
+	return ele.Value.(*node.GoffeineNode[K, V])
+}
+
+// budgets splits maximum between the regions: 1% for the window, then 20% of the main space for
+// probation and the rest for protected. Every region gets at least 1.
+func budgets(maximum int) (window, probation, protected int) {
+	window = max(maximum/100, 1)
+	probation = max((maximum-window)*20/100, 1)
+	protected = max(maximum-window-probation, 1)
+	return window, probation, protected
+}
+
+// setMaximum rebounds the cache to maximum, in weight when it is bounded by MaximumWeight, and
+// evicts down to the new budgets. The caller holds evictionLock.
+func (g *Goffeine[K, V]) setMaximum(maximum int) {
+	if maximum < 1 {
+		maximum = 3 // window: 1, probation: 1, protected: 1
+	}
+	if g.maximumWeight > 0 {
+		g.maximumWeight = maximum
+	} else {
+		g.maximumSize = maximum
+		g.fsketch.EnsureCapacity(maximum)
+	}
+	g.windowMaximumSize, g.probationMaximumSize, g.protectedMaximumSize = budgets(maximum)
+	g.climber.reset(maximum)
+	g.evictEntries()
+}
+
+// regionOf returns the access order list of the region that holds the node.
+func (g *Goffeine[K, V]) regionOf(gnode *node.GoffeineNode[K, V]) *list.List {
+	switch gnode.Position {
+	case node.ProbationPosition:
+		return &g.probation
+	case node.ProtectedPosition:
+		return &g.protected
+	default:
+		return &g.window
+	}
+}
+
+// regionWeightOf returns the running total weight of the region that holds the node.
+func (g *Goffeine[K, V]) regionWeightOf(gnode *node.GoffeineNode[K, V]) *int {
+	switch gnode.Position {
+	case node.ProbationPosition:
+		return &g.probationWeight
+	case node.ProtectedPosition:
+		return &g.protectedWeight
+	default:
+		return &g.windowWeight
+	}
+}
+
+// linkFront links the node to the front of the region at position and adds its weight to the
+// region's total.
+func (g *Goffeine[K, V]) linkFront(gnode *node.GoffeineNode[K, V], position node.Position) {
+	gnode.Position = position
+	*g.regionWeightOf(gnode) += gnode.Weight
+	gnode.AccessElement = g.regionOf(gnode).PushFront(gnode)
+}
+
+// unlink removes the node from its region and subtracts its weight from the region's total. The
+// entry stays in data.
+func (g *Goffeine[K, V]) unlink(gnode *node.GoffeineNode[K, V]) *node.GoffeineNode[K, V] {
+	g.regionOf(gnode).Remove(gnode.AccessElement)
+	gnode.AccessElement = nil
+	*g.regionWeightOf(gnode) -= gnode.Weight
+	return gnode
+}
+
+// setWeight changes the weight of a linked node and keeps its region's total accurate.
+func (g *Goffeine[K, V]) setWeight(gnode *node.GoffeineNode[K, V], weight int) {
+	*g.regionWeightOf(gnode) += weight - gnode.Weight
+	gnode.Weight = weight
+}
+
+// ensureSketchCapacity grows the frequency sketch when the cache holds more entries than it was
+// sized for, which happens to caches bounded by weight.
+func (g *Goffeine[K, V]) ensureSketchCapacity() {
+	if size := g.window.Len() + g.probation.Len() + g.protected.Len(); size > len(g.fsketch.Table) {
+		g.fsketch.EnsureCapacity(size)
+	}
+}
+
+// move records an access to the node. An entry hit in probation is promoted to protected, and
+// when protected overflows its least recently used entries are demoted to probation.
+func (g *Goffeine[K, V]) move(gnode *node.GoffeineNode[K, V]) {
+	switch gnode.Position {
+	case node.WindowPosition:
+		g.window.MoveToFront(gnode.AccessElement)
+	case node.ProbationPosition:
+		g.unlink(gnode)
+		g.linkFront(gnode, node.ProtectedPosition)
+		g.demoteFromProtected()
+	case node.ProtectedPosition:
+		g.protected.MoveToFront(gnode.AccessElement)
+	}
+}
+
+// demoteFromProtected moves protected's least recently used entries back to probation until
+// protected is within its maximum.
+func (g *Goffeine[K, V]) demoteFromProtected() {
+	for g.protectedWeight > g.protectedMaximumSize {
+		ele := g.protected.Back()
+		if ele == nil {
+			return
+		}
+		g.linkFront(g.unlink(nodeOf[K, V](ele)), node.ProbationPosition)
+	}
+}
+
+// evictEntries brings protected and the window back within their maximums, as an update may have
+// made an entry heavier, then evicts from the main space until the whole cache is within its
+// maximum.
+func (g *Goffeine[K, V]) evictEntries() {
+	g.demoteFromProtected()
+	candidates := g.evictFromWindow()
+	g.evictFromMain(candidates)
+}
+
+// evictFromWindow moves the window's least recently used entries into probation while the window
+// exceeds its maximum. They become the candidates for admission into the main space, and the
+// number of them is returned.
+func (g *Goffeine[K, V]) evictFromWindow() int {
+	candidates := 0
+	for g.windowWeight > g.windowMaximumSize {
+		ele := g.window.Back()
+		if ele == nil {
+			break
+		}
+		g.linkFront(g.unlink(nodeOf[K, V](ele)), node.ProbationPosition)
+		candidates++
+	}
+	return candidates
+}
+
+// evictFromMain evicts entries while the cache exceeds its maximum. The candidates that just left
+// the window are at the front of probation, and each of them has to beat the victim at the back of
+// probation in frequency; whichever of the two loses is evicted. Once the candidates run out, the
+// window's least recently used entry is the next candidate. Without any victim in probation the
+// victims are taken from protected, then from the window.
+func (g *Goffeine[K, V]) evictFromMain(candidates int) {
+	victimQueue := node.ProbationPosition
+	victim := g.probation.Back()
+	candidate := g.probation.Front()
+	for g.weightedSize() > g.maximum() {
+		// Search the admission window for additional candidates
+		if candidates == 0 {
+			candidate = g.window.Back()
+		}
+
+		// Try evicting from the protected and window queues
+		if candidate == nil && victim == nil {
+			if victimQueue == node.ProbationPosition {
+				victim = g.protected.Back()
+				victimQueue = node.ProtectedPosition
+				continue
+			} else if victimQueue == node.ProtectedPosition {
+				victim = g.window.Back()
+				victimQueue = node.WindowPosition
+				continue
+			}
+			break
+		}
+
+		// Skip over entries with zero weight
+		if victim != nil && weightOf[K, V](victim) == 0 {
+			victim = victim.Prev()
+			continue
+		} else if candidate != nil && weightOf[K, V](candidate) == 0 {
+			candidate = g.nextCandidate(candidate, candidates)
+			candidates--
+			continue
+		}
+
+		// Evict immediately if only one of the entries is present
+		if victim == nil {
+			evict := candidate
+			candidate = g.nextCandidate(candidate, candidates)
+			candidates--
+			g.evictEntry(nodeOf[K, V](evict), Size)
+			continue
+		} else if candidate == nil {
+			evict := victim
+			victim = victim.Prev()
+			g.evictEntry(nodeOf[K, V](evict), Size)
+			continue
+		}
+
+		// Evict immediately if both selected the same entry
+		if candidate == victim {
+			victim = victim.Prev()
+			g.evictEntry(nodeOf[K, V](candidate), Size)
+			candidate = nil
+			continue
+		}
+
+		// Evict immediately if the candidate's weight exceeds the maximum
+		if weightOf[K, V](candidate) > g.maximum() {
+			evict := candidate
+			candidate = g.nextCandidate(candidate, candidates)
+			candidates--
+			g.evictEntry(nodeOf[K, V](evict), Size)
+			continue
+		}
+
+		// Evict the entry with the lowest frequency
+		candidates--
+		candidateKey := nodeOf[K, V](candidate).Key
+		victimKey := nodeOf[K, V](victim).Key
+		if g.admit(candidateKey, victimKey) {
+			evict := victim
+			victim = victim.Prev()
+			g.evictEntry(nodeOf[K, V](evict), Size)
+			candidate = candidate.Next()
+		} else {
+			evict := candidate
+			candidate = g.nextCandidate(candidate, candidates)
+			g.evictEntry(nodeOf[K, V](evict), Size)
+		}
+	}
+}
+
+// nextCandidate returns the candidate that follows ele: the next older one that left the window
+// while candidates remain, and the window's next least recently used entry after that.
+func (g *Goffeine[K, V]) nextCandidate(ele *list.Element, candidates int) *list.Element {
+	if candidates > 0 {
+		return ele.Next()
+	}
+	return ele.Prev()
+}
+
+func weightOf[K comparable, V any](ele *list.Element) int {
+	return nodeOf[K, V](ele).Weight
+}
+
+// admit decides whether the candidate leaving the window should replace the victim at the back of
+// probation, based on how often each of them has been seen by the sketch.
+func (g *Goffeine[K, V]) admit(candidateKey K, victimKey K) bool {
+	candidateFreq := g.fsketch.Frequency(candidateKey)
+	victimFreq := g.fsketch.Frequency(victimKey)
+	if candidateFreq > victimFreq {
+		return true
+	}
+	if candidateFreq >= admitHashDosThreshold {
+		return rand.Int()&127 == 0
+	}
+	return false
+}
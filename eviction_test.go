@@ -0,0 +1,67 @@
+package goffeine
+
+import (
+	"container/list"
+	"github.com/stretchr/testify/assert"
+	"goffeine/internal/node"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// assertRegionWeights checks the running totals against the weights of the linked nodes.
+func assertRegionWeights[K comparable, V any](t *testing.T, g *Goffeine[K, V]) {
+	sum := func(region *list.List, position node.Position) int {
+		weight := 0
+		for ele := region.Front(); ele != nil; ele = ele.Next() {
+			gnode := ele.Value.(*node.GoffeineNode[K, V])
+			assert.Equal(t, position, gnode.Position)
+			weight += gnode.Weight
+		}
+		return weight
+	}
+	assert.Equal(t, sum(&g.window, node.WindowPosition), g.windowWeight)
+	assert.Equal(t, sum(&g.probation, node.ProbationPosition), g.probationWeight)
+	assert.Equal(t, sum(&g.protected, node.ProtectedPosition), g.protectedWeight)
+}
+
+func TestRegionWeightsStayAccurate(t *testing.T) {
+	cache := NewBuilder[int, string]().MaximumWeight(2000).
+		Weigher(func(key int, value string) uint32 { return uint32(len(value)) }).Build()
+	random := rand.New(rand.NewSource(42))
+	for i := 0; i < 20_000; i++ {
+		key := random.Intn(500)
+		switch random.Intn(10) {
+		case 0:
+			cache.Invalidate(key)
+		case 1, 2, 3:
+			cache.Put(key, strings.Repeat("x", random.Intn(100)))
+		default:
+			cache.Get(key)
+		}
+		assert.LessOrEqual(t, cache.WeightedSize(), 2000)
+	}
+	assertRegionWeights(t, cache)
+	assert.LessOrEqual(t, cache.protectedWeight, cache.protectedMaximumSize)
+	assert.LessOrEqual(t, cache.windowWeight, cache.windowMaximumSize)
+}
+
+func TestSketchGrowsWithAWeightedCache(t *testing.T) {
+	cache := NewBuilder[int, int]().MaximumWeight(1 << 20).
+		Weigher(func(key int, value int) uint32 { return 1 }).Build()
+	for i := 0; i < 1000; i++ {
+		cache.Put(i, i)
+	}
+	assert.GreaterOrEqual(t, len(cache.fsketch.Table), 1000)
+	assert.Less(t, len(cache.fsketch.Table), 1<<20)
+}
+
+func TestSetMaximumGrowsTheSketch(t *testing.T) {
+	cache := NewBuilder[int, int]().MaximumSize(10).Build()
+	cache.Policy().SetMaximum(5000)
+	assert.Equal(t, 8192, len(cache.fsketch.Table))
+	assert.Equal(t, 50000, cache.fsketch.SampleSize)
+
+	cache.Policy().SetMaximum(10)
+	assert.Equal(t, 8192, len(cache.fsketch.Table))
+}
@@ -0,0 +1,109 @@
+package goffeine
+
+import (
+	"goffeine/internal/node"
+	"math"
+	"time"
+)
+
+// maximumExpiry caps the durations returned by an Expiry, so deadlines never overflow the ticker.
+// It is about 146 years, which is as good as never.
+const maximumExpiry = time.Duration(math.MaxInt64 >> 1)
+
+// An Expiry calculates when an entry expires, so each entry can have its own time to live that
+// depends on its key and value. The currentTime is the Ticker's time in nanoseconds, and the
+// returned duration is the remaining lifetime of the entry; zero or less expires it right away.
+//
+// e.g.	the max-age of an upstream Cache-Control header, or the lifetime of a token
+type Expiry[K comparable, V any] interface {
+	// ExpireAfterCreate returns the lifetime of an entry that has just been created.
+	ExpireAfterCreate(key K, value V, currentTime int64) time.Duration
+	// ExpireAfterUpdate returns the lifetime of an entry whose value has just been replaced.
+	// Returning currentDuration keeps the entry's current deadline.
+	ExpireAfterUpdate(key K, value V, currentTime int64, currentDuration time.Duration) time.Duration
+	// ExpireAfterRead returns the lifetime of an entry that has just been read.
+	// Returning currentDuration keeps the entry's current deadline.
+	ExpireAfterRead(key K, value V, currentTime int64, currentDuration time.Duration) time.Duration
+}
+
+// expireAfterWrite sets the deadline of a node that has just been created or updated at now, with
+// the Expiry if there is one and with the ExpireAfterWrite setting otherwise.
+func (g *Goffeine[K, V]) expireAfterWrite(gnode *node.GoffeineNode[K, V], now int64, created bool) {
+	if g.expiry == nil {
+		g.scheduleExpiration(gnode, now, g.expireMilliseconds)
+		return
+	}
+	var duration time.Duration
+	if created {
+		duration = g.expiry.ExpireAfterCreate(gnode.Key, gnode.Value(), now)
+	} else {
+		duration = g.expiry.ExpireAfterUpdate(gnode.Key, gnode.Value(), now, g.remaining(gnode, now))
+	}
+	g.setVariableExpireTime(gnode, now, duration)
+}
+
+// expireAfterRead updates the deadline of a node that has just been read at now.
+func (g *Goffeine[K, V]) expireAfterRead(gnode *node.GoffeineNode[K, V], now int64) {
+	if g.expiry != nil {
+		duration := g.expiry.ExpireAfterRead(gnode.Key, gnode.Value(), now, g.remaining(gnode, now))
+		g.setVariableExpireTime(gnode, now, duration)
+	} else if g.accessMilliseconds > 0 {
+		g.updateExpireTime(gnode, now)
+	}
+}
+
+// remaining returns how long the node has left to live at now.
+func (g *Goffeine[K, V]) remaining(gnode *node.GoffeineNode[K, V], now int64) time.Duration {
+	expireTime := gnode.ExpireTime.Load()
+	if expireTime == 0 {
+		return maximumExpiry
+	}
+	return time.Duration(expireTime - now)
+}
+
+// setVariableExpireTime makes the node expire duration after now, as returned by the Expiry.
+func (g *Goffeine[K, V]) setVariableExpireTime(gnode *node.GoffeineNode[K, V], now int64, duration time.Duration) {
+	if duration > maximumExpiry {
+		duration = maximumExpiry
+	} else if duration < 0 {
+		duration = 0
+	}
+	expireTime := now + int64(duration)
+	gnode.WriteExpireTime.Store(expireTime)
+	gnode.ExpireTime.Store(expireTime)
+}
+
+// scheduleExpiration sets the node's write deadline expireMilliseconds after now. A non-positive
+// duration means writes do not expire the node.
+func (g *Goffeine[K, V]) scheduleExpiration(gnode *node.GoffeineNode[K, V], now int64, expireMilliseconds int64) {
+	if expireMilliseconds <= 0 {
+		gnode.WriteExpireTime.Store(0)
+	} else {
+		gnode.WriteExpireTime.Store(now + expireMilliseconds*int64(time.Millisecond))
+	}
+	g.updateExpireTime(gnode, now)
+}
+
+// updateExpireTime recomputes the node's deadline after an access at now, as the earlier of its
+// write deadline and the idle deadline.
+func (g *Goffeine[K, V]) updateExpireTime(gnode *node.GoffeineNode[K, V], now int64) {
+	expireTime := gnode.WriteExpireTime.Load()
+	if g.accessMilliseconds > 0 {
+		accessExpireTime := now + g.accessMilliseconds*int64(time.Millisecond)
+		if expireTime == 0 || accessExpireTime < expireTime {
+			expireTime = accessExpireTime
+		}
+	}
+	gnode.ExpireTime.Store(expireTime)
+}
+
+// reschedule links the node into the timer wheel at its current deadline, or unlinks it if it
+// never expires. The deadlines are set by readers and writers as they go, and the wheel catches up
+// with them when the policy applies the read or the write. The caller holds evictionLock.
+func (g *Goffeine[K, V]) reschedule(gnode *node.GoffeineNode[K, V]) {
+	if gnode.ExpireTime.Load() == 0 {
+		g.timerWheel.deschedule(gnode)
+	} else {
+		g.timerWheel.schedule(gnode)
+	}
+}
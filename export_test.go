@@ -0,0 +1,23 @@
+package goffeine
+
+// Frequency exposes the sketch's estimate for key to the external tests.
+func (g *Goffeine[K, V]) Frequency(key K) (frequency int) {
+	g.locked(func() { frequency = g.fsketch.Frequency(key) })
+	return frequency
+}
+
+// PendingRefreshes exposes to the external tests the count of the completed reloads that
+// maintenance has yet to apply.
+func (g *Goffeine[K, V]) PendingRefreshes() int {
+	return int(g.pendingRefreshes.Load())
+}
+
+// Waiters exposes to the external tests the count of the callers waiting for the load of key.
+func (c *LoadingCache[K, V]) Waiters(key K) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if l, ok := c.loads[key]; ok {
+		return l.waiters
+	}
+	return 0
+}
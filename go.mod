@@ -1,6 +1,6 @@
 module goffeine
 
-go 1.19
+go 1.24
 
 require github.com/stretchr/testify v1.8.2
 
@@ -3,6 +3,8 @@ package goffeine_test
 import (
 	"github.com/stretchr/testify/assert"
 	"goffeine"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -12,11 +14,11 @@ type CacheItem struct {
 	Bar string
 }
 
-func NewCache() *goffeine.Goffeine {
-	return goffeine.NewBuilder().MaximumSize(10_1000).ExpireAfterWrite(time.Minute, 5).RefreshAfterWrite(time.Hour, 1).Build()
+func NewCache() *goffeine.Goffeine[string, any] {
+	return goffeine.NewBuilder[string, any]().MaximumSize(10_1000).ExpireAfterWrite(time.Minute, 5).RefreshAfterWrite(time.Hour, 1).Build()
 }
-func NewCacheWithMaximumSize(maxSize int) *goffeine.Goffeine {
-	return goffeine.NewBuilder().MaximumSize(maxSize).ExpireAfterWrite(time.Minute, 5).RefreshAfterWrite(time.Hour, 1).Build()
+func NewCacheWithMaximumSize(maxSize int) *goffeine.Goffeine[string, any] {
+	return goffeine.NewBuilder[string, any]().MaximumSize(maxSize).ExpireAfterWrite(time.Minute, 5).RefreshAfterWrite(time.Hour, 1).Build()
 }
 
 func TestBuilder(t *testing.T) {
@@ -55,6 +57,30 @@ func TestBasicPutAndGet(t *testing.T) {
 	assert.False(t, ok)
 }
 
+type TenantKey struct {
+	Tenant string
+	ID     int
+}
+
+func TestStructKeyAndTypedValue(t *testing.T) {
+	cache := goffeine.NewBuilder[TenantKey, CacheItem]().MaximumSize(100).Build()
+	cache.Put(TenantKey{"acme", 1}, CacheItem{1, "a"})
+	cache.Put(TenantKey{"acme", 2}, CacheItem{2, "b"})
+	cache.Put(TenantKey{"initech", 1}, CacheItem{3, "c"})
+
+	v, ok := cache.Get(TenantKey{"acme", 1})
+	assert.True(t, ok)
+	assert.Equal(t, CacheItem{1, "a"}, v)
+
+	v, ok = cache.Get(TenantKey{"initech", 1})
+	assert.True(t, ok)
+	assert.Equal(t, "c", v.Bar)
+
+	v, ok = cache.Get(TenantKey{"initech", 2})
+	assert.False(t, ok)
+	assert.Equal(t, CacheItem{}, v)
+}
+
 func TestPutToAFullWindowAndGet(t *testing.T) {
 	// window maximum Size is 3,
 	// so we must put 3/0.01 maximum Size to cache
@@ -65,8 +91,10 @@ func TestPutToAFullWindowAndGet(t *testing.T) {
 	cache.Put("c", 3)
 	cache.Put("d", CacheItem{4, "d"})
 
+	// "a" is the window's victim, it is moved to probation which still has room
 	v, ok := cache.Get("a")
-	assert.False(t, ok)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
 
 	v, _ = cache.Get("b")
 	assert.Equal(t, 2, v)
@@ -78,3 +106,281 @@ func TestPutToAFullWindowAndGet(t *testing.T) {
 	assert.Equal(t, 4, v.(CacheItem).Foo)
 	assert.Equal(t, "d", v.(CacheItem).Bar)
 }
+
+func TestFrequentEntriesSurviveAScan(t *testing.T) {
+	cache := NewCacheWithMaximumSize(100)
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+	for n := 0; n < 5; n++ {
+		for i := 0; i < 10; i++ {
+			_, ok := cache.Get(strconv.Itoa(i))
+			assert.True(t, ok)
+		}
+	}
+
+	// one-hit wonders must lose the frequency contest against the hot entries
+	for i := 1000; i < 2000; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+	for i := 0; i < 10; i++ {
+		v, ok := cache.Get(strconv.Itoa(i))
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+}
+
+func TestCandidateWithHigherFrequencyIsAdmitted(t *testing.T) {
+	cache := NewCacheWithMaximumSize(3) // window: 1, probation: 1, protected: 1
+	cache.Put("a", 1)
+	cache.Put("b", 2) // a -> probation
+	cache.Put("c", 3) // b -> probation, main space is full now
+
+	for i := 0; i < 3; i++ {
+		cache.Put("d", 4) // d stays in the window and gets hot
+	}
+	cache.Put("e", 5) // d beats the probation victim a
+
+	_, ok := cache.Get("d")
+	assert.True(t, ok)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+}
+
+func TestProtectedOverflowIsDemotedToProbation(t *testing.T) {
+	cache := NewCacheWithMaximumSize(100) // window: 1, probation: 19, protected: 80
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+	// promote everything that left the window, protected overflows into probation
+	for i := 0; i < 99; i++ {
+		cache.Get(strconv.Itoa(i))
+	}
+	for i := 0; i < 100; i++ {
+		v, ok := cache.Get(strconv.Itoa(i))
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+}
+
+// A FakeTicker is advanced by hand. Loads and reloads in the background may read it meanwhile.
+type FakeTicker struct {
+	nanos atomic.Int64
+}
+
+func (t *FakeTicker) Read() int64                    { return t.nanos.Load() }
+func (t *FakeTicker) Advance(duration time.Duration) { t.nanos.Add(duration.Nanoseconds()) }
+
+func TestExpireAfterWrite(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Second, 10).Ticker(ticker).Build()
+	cache.Put("a", 1)
+	ticker.Advance(5 * time.Second)
+	cache.Put("b", 2)
+
+	ticker.Advance(5 * time.Second)
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	v, ok := cache.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+
+	ticker.Advance(5 * time.Second)
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+}
+
+func TestPutResetsTheWriteTime(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Second, 10).Ticker(ticker).Build()
+	cache.Put("a", 1)
+	ticker.Advance(9 * time.Second)
+	cache.Put("a", 2)
+	ticker.Advance(9 * time.Second)
+
+	v, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+}
+
+func TestPutWithDelay(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).Ticker(ticker).Build()
+	cache.PutWithDelay("a", 1, time.Minute.Milliseconds())
+	cache.Put("b", 2)
+
+	ticker.Advance(time.Hour)
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	_, ok = cache.Get("b")
+	assert.True(t, ok)
+}
+
+func TestExpireAfterAccess(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterAccess(10 * time.Second).Ticker(ticker).Build()
+	assert.Equal(t, (10 * time.Second).Milliseconds(), cache.AccessMilliseconds())
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+
+	// reading "a" keeps it alive, "b" stays idle
+	for i := 0; i < 3; i++ {
+		ticker.Advance(6 * time.Second)
+		_, ok := cache.Get("a")
+		assert.True(t, ok)
+	}
+	_, ok := cache.Get("b")
+	assert.False(t, ok)
+
+	ticker.Advance(10 * time.Second)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+}
+
+func TestExpireAfterAccessAndWrite(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).
+		ExpireAfterWrite(time.Second, 15).ExpireAfterAccess(10 * time.Second).Ticker(ticker).Build()
+	cache.Put("a", 1)
+
+	// the accesses keep "a" from going idle, but the write deadline still applies
+	ticker.Advance(8 * time.Second)
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+	ticker.Advance(6 * time.Second)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+	ticker.Advance(2 * time.Second)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+
+	// a write pushes the write deadline, the idle one is now the earlier
+	cache.Put("a", 2)
+	ticker.Advance(11 * time.Second)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+}
+
+// MaxAgeExpiry expires a response after its max-age, and keeps the deadline on reads
+type MaxAgeExpiry struct{}
+
+type Response struct {
+	Body   string
+	MaxAge time.Duration
+}
+
+func (MaxAgeExpiry) ExpireAfterCreate(key string, value Response, currentTime int64) time.Duration {
+	return value.MaxAge
+}
+
+func (MaxAgeExpiry) ExpireAfterUpdate(key string, value Response, currentTime int64, currentDuration time.Duration) time.Duration {
+	return value.MaxAge
+}
+
+func (MaxAgeExpiry) ExpireAfterRead(key string, value Response, currentTime int64, currentDuration time.Duration) time.Duration {
+	return currentDuration
+}
+
+func TestExpireAfter(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, Response]().MaximumSize(100).ExpireAfter(MaxAgeExpiry{}).Ticker(ticker).Build()
+	cache.Put("/a", Response{"a", 10 * time.Second})
+	cache.Put("/b", Response{"b", time.Minute})
+
+	ticker.Advance(5 * time.Second)
+	_, ok := cache.Get("/a")
+	assert.True(t, ok)
+
+	ticker.Advance(5 * time.Second)
+	_, ok = cache.Get("/a")
+	assert.False(t, ok)
+	v, ok := cache.Get("/b")
+	assert.True(t, ok)
+	assert.Equal(t, "b", v.Body)
+
+	// an update picks up the new max-age
+	cache.Put("/b", Response{"b2", time.Second})
+	ticker.Advance(time.Second)
+	_, ok = cache.Get("/b")
+	assert.False(t, ok)
+}
+
+func TestExpireAfterOverridesExpireAfterWrite(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, Response]().MaximumSize(100).
+		ExpireAfterWrite(time.Second, 1).ExpireAfter(MaxAgeExpiry{}).Ticker(ticker).Build()
+	cache.Put("/a", Response{"a", time.Hour})
+	ticker.Advance(time.Minute)
+	_, ok := cache.Get("/a")
+	assert.True(t, ok)
+
+	// an explicit delay still wins for its own write
+	cache.PutWithDelay("/a", Response{"a", time.Hour}, time.Second.Milliseconds())
+	ticker.Advance(time.Second)
+	_, ok = cache.Get("/a")
+	assert.False(t, ok)
+}
+
+func TestInvalidate(t *testing.T) {
+	cache := NewCacheWithMaximumSize(100)
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+	for i := 0; i < 50; i++ {
+		cache.Get(strconv.Itoa(i)) // some are in protected now
+	}
+
+	cache.Invalidate("0")  // protected
+	cache.Invalidate("60") // probation
+	cache.Invalidate("99") // window
+	cache.Invalidate("missing")
+	for _, key := range []string{"0", "60", "99"} {
+		_, ok := cache.Get(key)
+		assert.False(t, ok)
+	}
+	_, ok := cache.Get("1")
+	assert.True(t, ok)
+
+	// the room is reused without evicting anyone
+	cache.Put("a", "a")
+	cache.Put("b", "b")
+	cache.Put("c", "c")
+	for i := 1; i < 99; i++ {
+		if i != 60 {
+			_, ok := cache.Get(strconv.Itoa(i))
+			assert.True(t, ok)
+		}
+	}
+}
+
+func TestInvalidateIfAndInvalidateAll(t *testing.T) {
+	cache := NewCacheWithMaximumSize(100)
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+	cache.InvalidateIf(func(key string, value any) bool { return value.(int)%2 == 0 })
+	for i := 0; i < 100; i++ {
+		_, ok := cache.Get(strconv.Itoa(i))
+		assert.Equal(t, i%2 == 1, ok)
+	}
+
+	cache.InvalidateAll()
+	for i := 0; i < 100; i++ {
+		_, ok := cache.Get(strconv.Itoa(i))
+		assert.False(t, ok)
+	}
+}
+
+func TestInvalidateDropsTheExpiration(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Second, 1).Ticker(ticker).Build()
+	cache.Put("a", 1)
+	cache.Invalidate("a")
+	cache.Put("a", 2)
+	ticker.Advance(500 * time.Millisecond)
+	cache.CleanUp()
+
+	v, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+}
@@ -3,14 +3,31 @@ package goffeine
 import (
 	"container/list"
 	"goffeine/internal/node"
+	"hash/maphash"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // A Goffeine represents a cache
 // It is implemented with Window-TinyLFU algorithm
-type Goffeine struct {
-	fsketch              *FrequencySketch
+// The maximum sizes of the window, probation and protected regions are weights when the cache is
+// bounded by MaximumWeight, and entry counts otherwise.
+//
+// A Goffeine is safe for concurrent use. Reads and writes update data right away, and are recorded
+// into the read and write buffers; the eviction policy catches up with them in maintenance, which
+// drains both buffers under evictionLock. evictionLock guards the regions, their weights and
+// maximums, the sketch, the timer wheel and the hill climber. The writes of a key are serialized by
+// its key lock, which maintenance never takes.
+type Goffeine[K comparable, V any] struct {
+	fsketch              *FrequencySketch[K]
 	data                 *sync.Map
+	size                 atomic.Int64
+	keyLocks             []stripedLock
+	keySeed              maphash.Seed
+	evictionLock         sync.Mutex
+	readBuffer           *readBuffer[node.GoffeineNode[K, V]]
+	writeBuffer          chan func()
 	maximumSize          int
 	window               list.List
 	windowMaximumSize    int
@@ -18,110 +35,600 @@ type Goffeine struct {
 	probationMaximumSize int
 	protected            list.List
 	protectedMaximumSize int
+	maximumWeight        int
+	weigher              Weigher[K, V]
+	windowWeight         int
+	probationWeight      int
+	protectedWeight      int
 	expireMilliseconds   int64
 	refreshMilliseconds  int64
+	accessMilliseconds   int64
+	staleMilliseconds    int64
+	expiry               Expiry[K, V]
+	ticker               Ticker
+	timerWheel           *timerWheel[K, V]
+	loader               CacheLoader[K, V]
+	failureMilliseconds  int64
+	notFoundMilliseconds int64
+	negativeWeight       int
+	refreshLock          sync.Mutex
+	refreshed            []refreshResult[K, V]
+	pendingRefreshes     atomic.Int32
+	removalListener      RemovalListener[K, V]
+	removals             []removal[K, V]
+	stats                *statsCounter
+	climber              *hillClimber
 }
 
-func (g *Goffeine) MaximumSize() int           { return g.maximumSize }
-func (g *Goffeine) ExpireMilliseconds() int64  { return g.expireMilliseconds }
-func (g *Goffeine) RefreshMilliseconds() int64 { return g.refreshMilliseconds }
-func (g *Goffeine) WindowMaximumSize() int     { return g.windowMaximumSize }
-func (g *Goffeine) ProbationMaximumSize() int  { return g.probationMaximumSize }
-func (g *Goffeine) ProtectedMaximumSize() int  { return g.protectedMaximumSize }
+func (g *Goffeine[K, V]) MaximumSize() (size int) {
+	g.locked(func() { size = g.maximumSize })
+	return size
+}
 
-func (g *Goffeine) windowIsFull() bool    { return g.window.Len() == g.windowMaximumSize }
-func (g *Goffeine) probationIsFull() bool { return g.probation.Len() == g.probationMaximumSize }
-func (g *Goffeine) protectedIsFull() bool { return g.protected.Len() == g.protectedMaximumSize }
+func (g *Goffeine[K, V]) MaximumWeight() (weight int) {
+	g.locked(func() { weight = g.maximumWeight })
+	return weight
+}
 
-func (g *Goffeine) Get(key string) (any, bool) {
-	ele, ok := g.data.Load(key)
-	if !ok {
-		return nil, false
+func (g *Goffeine[K, V]) ExpireMilliseconds() int64  { return g.expireMilliseconds }
+func (g *Goffeine[K, V]) RefreshMilliseconds() int64 { return g.refreshMilliseconds }
+func (g *Goffeine[K, V]) AccessMilliseconds() int64  { return g.accessMilliseconds }
+
+func (g *Goffeine[K, V]) WindowMaximumSize() (size int) {
+	g.locked(func() { size = g.windowMaximumSize })
+	return size
+}
+
+func (g *Goffeine[K, V]) ProbationMaximumSize() (size int) {
+	g.locked(func() { size = g.probationMaximumSize })
+	return size
+}
+
+func (g *Goffeine[K, V]) ProtectedMaximumSize() (size int) {
+	g.locked(func() { size = g.protectedMaximumSize })
+	return size
+}
+
+func (g *Goffeine[K, V]) WindowSize() (size int) {
+	g.locked(func() { size = g.window.Len() })
+	return size
+}
+
+func (g *Goffeine[K, V]) ProbationSize() (size int) {
+	g.locked(func() { size = g.probation.Len() })
+	return size
+}
+
+func (g *Goffeine[K, V]) ProtectedSize() (size int) {
+	g.locked(func() { size = g.protected.Len() })
+	return size
+}
+
+// EstimatedSize returns the number of entries in the cache, including the expired ones that
+// maintenance has not reclaimed yet.
+func (g *Goffeine[K, V]) EstimatedSize() int {
+	return int(g.size.Load())
+}
+
+// WeightedSize returns the total weight of the entries in the cache, which is the number of
+// entries unless the cache has a Weigher.
+func (g *Goffeine[K, V]) WeightedSize() (size int) {
+	g.locked(func() { size = g.weightedSize() })
+	return size
+}
+
+// weightedSize returns the total weight of the regions. The caller holds evictionLock.
+func (g *Goffeine[K, V]) weightedSize() int {
+	return g.windowWeight + g.probationWeight + g.protectedWeight
+}
+
+func (g *Goffeine[K, V]) Get(key K) (V, bool) {
+	value, _, ok := g.GetWithStale(key)
+	return value, ok
+}
+
+// GetWithStale is Get, and also reports whether the value is stale: it expired, and is served
+// during the grace period of ServeStale while it is reloaded.
+func (g *Goffeine[K, V]) GetWithStale(key K) (value V, stale bool, ok bool) {
+	value, ok, stale, err := g.getEntry(key)
+	if ok && err == nil {
+		g.stats.recordHits(1)
+		return value, stale, true
 	}
-	gnode := ele.(*list.Element).Value.(*node.GoffeineNode)
-	defer func() {
-		go g.move(gnode)
-	}()
-	return gnode.Value, true
+	g.stats.recordMisses(1)
+	var zero V
+	return zero, false, false
 }
 
-func (g *Goffeine) Put(key string, value any) {
-	g.put(key, value, g.expireMilliseconds)
+// getIfPresent is Get without the statistics. A cached failure is absent.
+func (g *Goffeine[K, V]) getIfPresent(key K) (V, bool) {
+	value, ok, _, err := g.getEntry(key)
+	return value, ok && err == nil
 }
 
-func (g *Goffeine) PutWithDelay(key string, value any, delayMilliseconds int64) {
-	g.put(key, value, delayMilliseconds)
+// getEntry looks up the entry of key without the statistics. It returns the error of the entry if
+// it is a cached failure.
+func (g *Goffeine[K, V]) getEntry(key K) (value V, ok bool, stale bool, err error) {
+	v, ok := g.data.Load(key)
+	if !ok {
+		return value, false, false, nil
+	}
+	gnode := v.(*node.GoffeineNode[K, V])
+	var now int64
+	if g.readsTicker(gnode) {
+		now = g.ticker.Read()
+	}
+	return g.read(gnode, now)
 }
 
-func (g *Goffeine) put(key string, value any, expireMilliseconds int64) {
-	gnode := node.New(key, value, node.WindowPosition)
+// peek looks up the entry of key like getEntry, but neither records the read nor runs maintenance,
+// so it is safe to call under a lock that the removal listener may take.
+func (g *Goffeine[K, V]) peek(key K) (value V, ok bool, stale bool, err error) {
 	v, ok := g.data.Load(key)
-	if ok {
-		oldEle := v.(*list.Element)
-		gnode.Position = oldEle.Value.(*node.GoffeineNode).Position
-		oldEle.Value = gnode
-
-		if gnode.Position == node.WindowPosition {
-			g.window.MoveToFront(oldEle)
-		} else if gnode.Position == node.ProbationPosition {
-			// todo
-		} else if gnode.Position == node.ProtectedPosition {
-			// todo
+	if !ok {
+		return value, false, false, nil
+	}
+	gnode := v.(*node.GoffeineNode[K, V])
+	value, err = gnode.Load()
+	if gnode.ExpireTime.Load() != 0 {
+		if now := g.ticker.Read(); gnode.IsExpired(now) {
+			if err != nil || !g.isStale(gnode, now) {
+				var zero V
+				return zero, false, false, nil
+			}
+			stale = true
 		}
-		return
 	}
+	return value, true, stale, err
+}
 
-	g.putToWindow(key, gnode)
-	return
+// GetAll returns the entries of keys that are present in the cache, reading the clock once for the
+// whole batch.
+func (g *Goffeine[K, V]) GetAll(keys []K) map[K]V {
+	entries, _ := g.getAll(keys)
+	g.stats.recordHits(len(entries))
+	g.stats.recordMisses(len(keys) - len(entries))
+	return entries
 }
 
-func (g *Goffeine) putToWindow(key string, gnode *node.GoffeineNode) {
-	// not exist
-	var ele *list.Element
-	if g.windowIsFull() {
-		// remove the key data
-		ele = g.window.Back()
-		oldKey := ele.Value.(*node.GoffeineNode).Key
-		g.data.Delete(oldKey)
+// getAll is GetAll without the statistics. It returns the cached failures of keys apart.
+func (g *Goffeine[K, V]) getAll(keys []K) (map[K]V, map[K]error) {
+	now := g.ticker.Read()
+	entries := make(map[K]V, len(keys))
+	var failures map[K]error
+	for _, key := range keys {
+		v, ok := g.data.Load(key)
+		if !ok {
+			continue
+		}
+		value, ok, _, err := g.read(v.(*node.GoffeineNode[K, V]), now)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			if failures == nil {
+				failures = make(map[K]error)
+			}
+			failures[key] = err
+		} else {
+			entries[key] = value
+		}
+	}
+	return entries, failures
+}
+
+// read records an access at now to the entry of gnode and returns its value, or its error if it
+// is a cached failure, unless it has expired. An expired value is still returned as stale during
+// the grace period of ServeStale. now is only used when readsTicker is true for the entry.
+func (g *Goffeine[K, V]) read(gnode *node.GoffeineNode[K, V], now int64) (value V, ok bool, stale bool, err error) {
+	value, err = gnode.Load()
+	if g.readsTicker(gnode) {
+		if g.pendingRefreshes.Load() > 0 {
+			g.scheduleDrainBuffers()
+		}
+		if gnode.IsExpired(now) {
+			if err != nil || !g.isStale(gnode, now) {
+				// invisible right away, it is reclaimed by the next maintenance
+				var zero V
+				return zero, false, false, nil
+			}
+			// served as it is, the read neither extends it nor waits for the reload
+			g.reload(gnode)
+			stale = true
+		} else if err == nil {
+			// a cached failure lives for its own time to live, and is not reloaded
+			g.expireAfterRead(gnode, now)
+			g.refreshIfNeeded(gnode, now)
+		}
+	}
+	g.afterRead(gnode)
+	return value, true, stale, err
+}
+
+// isStale reports whether an expired node is still within the grace period of ServeStale at now.
+func (g *Goffeine[K, V]) isStale(gnode *node.GoffeineNode[K, V], now int64) bool {
+	return g.staleMilliseconds > 0 && now < gnode.ExpireTime.Load()+g.staleMilliseconds*int64(time.Millisecond)
+}
+
+// afterRead records the read of gnode into the read buffer, and runs maintenance when the buffer
+// is full or writes are waiting for it.
+func (g *Goffeine[K, V]) afterRead(gnode *node.GoffeineNode[K, V]) {
+	if g.readBuffer.offer(gnode) == offerFull || len(g.writeBuffer) > 0 {
+		g.scheduleDrainBuffers()
+	}
+}
+
+// Stats returns a snapshot of the cache's statistics. They are all zero unless the cache was built
+// with RecordStats.
+func (g *Goffeine[K, V]) Stats() CacheStats {
+	return g.stats.snapshot()
+}
+
+// readsTicker reports whether a read of the node has to know the current time.
+func (g *Goffeine[K, V]) readsTicker(gnode *node.GoffeineNode[K, V]) bool {
+	return gnode.ExpireTime.Load() != 0 || g.accessMilliseconds > 0 || g.expiry != nil || g.refreshes()
+}
+
+func (g *Goffeine[K, V]) Put(key K, value V) {
+	g.put(key, value, g.expireAfterWrite)
+}
+
+// PutWithDelay puts the entry with its own time to live, which overrides the cache's expiration
+// settings for this write.
+func (g *Goffeine[K, V]) PutWithDelay(key K, value V, delayMilliseconds int64) {
+	g.put(key, value, func(gnode *node.GoffeineNode[K, V], now int64, _ bool) {
+		g.scheduleExpiration(gnode, now, delayMilliseconds)
+	})
+}
+
+// PutAll puts all the entries, then admits them as one batch in a single maintenance pass.
+func (g *Goffeine[K, V]) PutAll(entries map[K]V) {
+	now := g.ticker.Read()
+	for key, value := range entries {
+		g.writeKey(key, value, nil, now, g.expireAfterWrite)
+	}
+	g.scheduleDrainBuffers()
+}
+
+// put creates or updates the entry for key, and lets expire set the deadline of its node.
+func (g *Goffeine[K, V]) put(key K, value V, expire func(gnode *node.GoffeineNode[K, V], now int64, created bool)) {
+	g.writeKey(key, value, nil, g.ticker.Read(), expire)
+	g.scheduleDrainBuffers()
+}
+
+// writeKey is write under the lock of key, followed by the notification of the replaced value and
+// the buffering of the write.
+func (g *Goffeine[K, V]) writeKey(key K, value V, err error, now int64, expire func(gnode *node.GoffeineNode[K, V], now int64, created bool)) {
+	lock := g.keyLock(key)
+	lock.Lock()
+	task, replaced := g.write(key, value, err, now, expire)
+	lock.Unlock()
+	g.deliver(replaced)
+	g.bufferWrite(task)
+}
+
+// write creates or updates the entry for key at now in data, and returns the task that brings the
+// policy up to date with it along with the value it replaced, if any, for the caller to deliver. A
+// non-nil err makes the entry a cached failure instead of a value. The caller holds the key lock.
+func (g *Goffeine[K, V]) write(key K, value V, err error, now int64, expire func(gnode *node.GoffeineNode[K, V], now int64, created bool)) (func(), *removal[K, V]) {
+	g.keyLock(key).writes++
+	weight := g.negativeWeight
+	if err == nil {
+		weight = g.weigh(key, value)
+	}
+	for {
+		if v, ok := g.data.Load(key); ok {
+			gnode := v.(*node.GoffeineNode[K, V])
+			gnode.Lock()
+			if !gnode.IsAlive() {
+				// removed meanwhile, so the next attempt creates the entry
+				gnode.Unlock()
+				continue
+			}
+			oldValue, oldErr := gnode.Load()
+			setEntry(gnode, value, err)
+			gnode.WriteWeight.Store(int64(weight))
+			gnode.WriteTime.Store(now)
+			// a value replacing a cached failure is as good as created
+			expire(gnode, now, oldErr != nil)
+			gnode.Unlock()
+			task := func() { g.onUpdate(gnode) }
+			if oldErr != nil {
+				return task, nil
+			}
+			return task, &removal[K, V]{key, oldValue, Replaced}
+		}
 
-		// put value to back element and move it to front
-		ele.Value = gnode
-		g.window.MoveToFront(ele)
+		gnode := node.New(key, value, node.WindowPosition)
+		setEntry(gnode, value, err)
+		gnode.WriteWeight.Store(int64(weight))
+		gnode.WriteTime.Store(now)
+		expire(gnode, now, true)
+		if _, loaded := g.data.LoadOrStore(key, gnode); loaded {
+			continue
+		}
+		g.size.Add(1)
+		return func() { g.onAdd(gnode) }, nil
+	}
+}
+
+// setEntry sets the value of the node, or makes it a cached failure if err is not nil.
+func setEntry[K comparable, V any](gnode *node.GoffeineNode[K, V], value V, err error) {
+	if err != nil {
+		gnode.SetErr(err)
 	} else {
-		ele = g.window.PushFront(gnode)
-	}
-	g.data.Store(key, ele)
-
-	//if g.windowIsFull() && g.probationIsFull() { // window 满了和probation都满了
-	//	ele1 := g.window.Back()
-	//	ele2 := g.probation.Back()
-	//	g.evict(key, ele1, ele2)
-	//}
-	////else if g.window.Len() > g.windowSize
-	////if g.window.Len() > g.windowSize { // window 满了，则要考虑和probation一起进行处理
-	////	ele1 := g.window.Back()
-	////	ele2 := g.probation.Back()
-	////	g.compete(ele1, ele2)
-	////}
-	////g.data.Store(key, ele)
-	//return ele
-	//ele := v.(*list.Element)
-	//ele.Value = value
-}
-
-func (g *Goffeine) move(gnode *node.GoffeineNode) {
-	if gnode.Position == node.WindowPosition {
-		// todo move to probation
+		gnode.SetValue(value)
+	}
+}
+
+// Invalidate removes the entry of key, if there is one, and keeps a load of key in flight from
+// caching what it read before.
+func (g *Goffeine[K, V]) Invalidate(key K) {
+	lock := g.keyLock(key)
+	lock.Lock()
+	v, ok := g.data.Load(key)
+	if !ok {
+		// a load of key in flight must not bring back what it read before
+		lock.writes++
+		lock.Unlock()
+		return
+	}
+	task, removed := g.remove(v.(*node.GoffeineNode[K, V]), Explicit)
+	lock.Unlock()
+	if task != nil {
+		g.deliver(removed)
+		g.bufferWrite(task)
+		g.scheduleDrainBuffers()
+	}
+}
+
+// A stripedLock serializes the writes of the keys that hash to it, and counts them, so a load can
+// tell whether its key was written or removed while it ran.
+type stripedLock struct {
+	sync.Mutex
+	writes uint64 // guarded by the mutex
+}
+
+// keyLock returns the lock that serializes the writes of key. The locks are striped, so a writer
+// holding one must not write to the cache again.
+func (g *Goffeine[K, V]) keyLock(key K) *stripedLock {
+	return &g.keyLocks[maphash.Comparable(g.keySeed, key)&uint64(len(g.keyLocks)-1)]
+}
+
+// keyWrites returns the count of writes of the stripe of key, for publishLoaded.
+func (g *Goffeine[K, V]) keyWrites(key K) uint64 {
+	lock := g.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+	return lock.writes
+}
+
+// InvalidateAll removes every entry, the cached failures included.
+func (g *Goffeine[K, V]) InvalidateAll() {
+	g.invalidateIf(func(*node.GoffeineNode[K, V]) bool { return true })
+}
+
+// InvalidateIf removes the entries for which pred returns true. It leaves the cached failures
+// alone, which have no value to test.
+func (g *Goffeine[K, V]) InvalidateIf(pred func(key K, value V) bool) {
+	g.invalidateIf(func(gnode *node.GoffeineNode[K, V]) bool {
+		value, err := gnode.Load()
+		return err == nil && pred(gnode.Key, value)
+	})
+}
+
+func (g *Goffeine[K, V]) invalidateIf(pred func(gnode *node.GoffeineNode[K, V]) bool) {
+	g.data.Range(func(_, v any) bool {
+		gnode := v.(*node.GoffeineNode[K, V])
+		if pred(gnode) {
+			g.invalidate(gnode)
+		}
+		return true
+	})
+	g.scheduleDrainBuffers()
+}
+
+// invalidate removes gnode from data under its key lock, unless it was removed already, and
+// buffers its removal from the policy.
+func (g *Goffeine[K, V]) invalidate(gnode *node.GoffeineNode[K, V]) {
+	lock := g.keyLock(gnode.Key)
+	lock.Lock()
+	task, removed := g.remove(gnode, Explicit)
+	lock.Unlock()
+	if task != nil {
+		g.deliver(removed)
+		g.bufferWrite(task)
+	}
+}
+
+// remove removes gnode from data because of cause, unless it was removed already. It returns the
+// task that removes the node from the policy, nil if there is nothing to do, along with the removed
+// value, if any, for the caller to deliver. The caller holds the key lock.
+func (g *Goffeine[K, V]) remove(gnode *node.GoffeineNode[K, V], cause RemovalCause) (func(), *removal[K, V]) {
+	g.keyLock(gnode.Key).writes++
+	gnode.Lock()
+	if !gnode.IsAlive() {
+		gnode.Unlock()
+		return nil, nil
+	}
+	g.retire(gnode)
+	value, err := gnode.Load()
+	gnode.Unlock()
+	task := func() { g.onRemove(gnode) }
+	if err != nil {
+		return task, nil
+	}
+	return task, &removal[K, V]{gnode.Key, value, cause}
+}
+
+// retire removes gnode from data. The caller holds the node's lock, and the node is alive.
+func (g *Goffeine[K, V]) retire(gnode *node.GoffeineNode[K, V]) {
+	g.data.CompareAndDelete(gnode.Key, gnode)
+	g.size.Add(-1)
+	gnode.Retire()
+}
+
+// CleanUp performs any pending maintenance, such as reclaiming the expired entries.
+func (g *Goffeine[K, V]) CleanUp() {
+	g.locked(func() {})
+}
+
+// bufferWrite adds a task to the write buffer. When the buffer is full, the writer waits for
+// maintenance to drain it instead.
+func (g *Goffeine[K, V]) bufferWrite(task func()) {
+	for !g.offerWrite(task) {
+		g.CleanUp()
+	}
+}
+
+// offerWrite adds a task to the write buffer unless it is full, and reports whether it did.
+func (g *Goffeine[K, V]) offerWrite(task func()) bool {
+	select {
+	case g.writeBuffer <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// scheduleDrainBuffers runs maintenance unless another goroutine is running it already. That one
+// catches up with the writes buffered meanwhile before it gives up.
+func (g *Goffeine[K, V]) scheduleDrainBuffers() {
+	for g.evictionLock.TryLock() {
+		g.maintenance(g.ticker.Read())
+		g.unlock()
+		if len(g.writeBuffer) == 0 {
+			return
+		}
+	}
+}
+
+// locked runs maintenance and then f under evictionLock, so f sees every read and write buffered
+// before.
+func (g *Goffeine[K, V]) locked(f func()) {
+	g.evictionLock.Lock()
+	g.maintenance(g.ticker.Read())
+	f()
+	g.unlock()
+}
+
+// unlock releases evictionLock, then notifies the removal listener of the removals made under it,
+// so the listener is free to use the cache.
+func (g *Goffeine[K, V]) unlock() {
+	removals := g.removals
+	g.removals = nil
+	g.evictionLock.Unlock()
+	for _, r := range removals {
+		g.notifyRemoval(r.key, r.value, r.cause)
+	}
+}
+
+// maintenance brings the policy up to date with the buffered reads and writes, applies the
+// completed reloads, advances the timer wheel to now, which evicts the entries that have expired,
+// evicts down to the maximum and lets the hill climber resize the window. The caller holds
+// evictionLock.
+func (g *Goffeine[K, V]) maintenance(now int64) {
+	g.readBuffer.drainTo(g.onAccess)
+	g.drainWriteBuffer()
+	g.applyRefreshes(now)
+	g.timerWheel.advance(g, now)
+	g.evictEntries()
+	g.climb()
+}
+
+// drainWriteBuffer runs the buffered write tasks, at most as many as the buffer holds so that
+// writers can not keep maintenance busy forever.
+func (g *Goffeine[K, V]) drainWriteBuffer() {
+	for i := 0; i < cap(g.writeBuffer); i++ {
+		select {
+		case task := <-g.writeBuffer:
+			task()
+		default:
+			return
+		}
+	}
+}
+
+// onAccess applies a buffered read of gnode to the policy.
+func (g *Goffeine[K, V]) onAccess(gnode *node.GoffeineNode[K, V]) {
+	if gnode.AccessElement == nil {
+		// its addition is still buffered, or it has been removed
+		return
+	}
+	g.fsketch.Increment(gnode.Key)
+	g.climber.recordHit()
+	if g.accessMilliseconds > 0 || g.expiry != nil {
+		g.reschedule(gnode)
+	}
+	g.move(gnode)
+}
+
+// onAdd links a node created by a write into the window.
+func (g *Goffeine[K, V]) onAdd(gnode *node.GoffeineNode[K, V]) {
+	if !gnode.IsAlive() {
+		// removed before the policy got to add it
 		return
 	}
-	if gnode.Position == node.ProbationPosition {
-		// todo move to protected
+	g.fsketch.Increment(gnode.Key)
+	g.climber.recordMiss()
+	gnode.Weight = int(gnode.WriteWeight.Load())
+	g.linkFront(gnode, node.WindowPosition)
+	g.reschedule(gnode)
+	g.ensureSketchCapacity()
+}
+
+// onUpdate applies a write to an existing entry to the policy.
+func (g *Goffeine[K, V]) onUpdate(gnode *node.GoffeineNode[K, V]) {
+	if gnode.AccessElement == nil {
+		// its addition is still buffered and catches up with the update, or it has been removed
 		return
 	}
-	return
+	g.fsketch.Increment(gnode.Key)
+	g.setWeight(gnode, int(gnode.WriteWeight.Load()))
+	g.reschedule(gnode)
+	g.move(gnode)
 }
 
-//
-//func (g *Goffeine) evict(key string, wEle *list.Element, pbEle *list.Element) {
-//	cnt = g.counter[key]
-//}
+// onRemove unlinks a node removed from data from the policy.
+func (g *Goffeine[K, V]) onRemove(gnode *node.GoffeineNode[K, V]) {
+	if gnode.AccessElement != nil {
+		g.unlink(gnode)
+	}
+	g.timerWheel.deschedule(gnode)
+}
+
+// evictExpired removes an expired node handed over by the timer wheel. It returns false if a
+// write renewed the node meanwhile, so the wheel must reschedule it.
+func (g *Goffeine[K, V]) evictExpired(gnode *node.GoffeineNode[K, V]) bool {
+	return g.evictEntry(gnode, Expired)
+}
+
+// evictEntry removes gnode from data and from the policy because of cause, and queues the
+// notification of the removal listener. A node that was removed from data already is only dropped
+// from the policy. It returns false if an expired node was renewed meanwhile. The caller holds
+// evictionLock.
+func (g *Goffeine[K, V]) evictEntry(gnode *node.GoffeineNode[K, V], cause RemovalCause) bool {
+	gnode.Lock()
+	alive := gnode.IsAlive()
+	if alive && cause == Expired && !g.timerWheel.isDue(gnode) {
+		gnode.Unlock()
+		return false
+	}
+	if alive {
+		g.retire(gnode)
+	}
+	value, err := gnode.Load()
+	gnode.Unlock()
+
+	g.onRemove(gnode)
+	if alive {
+		if cause.WasEvicted() {
+			g.stats.recordEviction(gnode.Weight)
+		}
+		if err == nil {
+			// the listener hears of values only, not of cached failures
+			g.removals = append(g.removals, removal[K, V]{gnode.Key, value, cause})
+		}
+	}
+	return true
+}
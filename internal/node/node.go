@@ -1,5 +1,11 @@
 package node
 
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
 type Position int
 
 const (
@@ -8,12 +14,94 @@ const (
 	ProtectedPosition
 )
 
-type GoffeineNode struct {
-	Key      string
-	Value    any
+// A GoffeineNode is a cache entry. Readers use it without any lock, so the fields they read are
+// atomic; the policy fields are guarded by the cache's eviction lock, and writers hold the node's
+// own lock to update an entry that is still alive.
+type GoffeineNode[K comparable, V any] struct {
+	Key   K
+	value atomic.Pointer[entry[V]]
+
+	// Position, Weight and AccessElement are guarded by the cache's eviction lock.
 	Position Position
+	// Weight is the weight of the entry as counted by the eviction policy. It catches up with
+	// WriteWeight when the policy applies the write.
+	Weight int
+	// AccessElement links the node into the access order list of its region, nil until the policy
+	// has added it and once it has removed it.
+	AccessElement *list.Element
+
+	// WriteWeight is the weight calculated by the cache's Weigher on the last write, 1 without one.
+	WriteWeight atomic.Int64
+	// WriteTime is the ticker time in nanoseconds of the last write of the value.
+	WriteTime atomic.Int64
+	// Refreshing is set while a background reload of the entry is in flight.
+	Refreshing atomic.Bool
+
+	// ExpireTime is the ticker time in nanoseconds at which the entry expires, 0 if it never does.
+	ExpireTime atomic.Int64
+	// WriteExpireTime is the part of ExpireTime set by the last write, 0 if writes do not expire.
+	WriteExpireTime atomic.Int64
+	// TimerElement and TimerBucket link the node into the timer wheel while it is scheduled. They
+	// are guarded by the cache's eviction lock.
+	TimerElement *list.Element
+	TimerBucket  *list.List
+
+	sync.Mutex
+	retired atomic.Bool
+}
+
+// An entry is what a node holds, a value or the error of a failed load, swapped as one so readers
+// never see a mix of both.
+type entry[V any] struct {
+	value V
+	err   error
+}
+
+func New[K comparable, V any](key K, value V, position Position) *GoffeineNode[K, V] {
+	n := &GoffeineNode[K, V]{Key: key, Position: position, Weight: 1}
+	n.SetValue(value)
+	n.WriteWeight.Store(1)
+	return n
+}
+
+// Value returns the current value of the entry, the zero value if it is a cached failure.
+func (n *GoffeineNode[K, V]) Value() V {
+	return n.value.Load().value
+}
+
+// Err returns the error of the failed load that the entry caches, nil if it holds a value.
+func (n *GoffeineNode[K, V]) Err() error {
+	return n.value.Load().err
+}
+
+// Load returns the current value of the entry along with its error, read together.
+func (n *GoffeineNode[K, V]) Load() (V, error) {
+	e := n.value.Load()
+	return e.value, e.err
+}
+
+// SetValue replaces the value of the entry, or the failure it caches.
+func (n *GoffeineNode[K, V]) SetValue(value V) {
+	n.value.Store(&entry[V]{value: value})
+}
+
+// SetErr turns the entry into a cached failure of its load.
+func (n *GoffeineNode[K, V]) SetErr(err error) {
+	n.value.Store(&entry[V]{err: err})
+}
+
+// IsExpired reports whether the entry has expired at the given ticker time.
+func (n *GoffeineNode[K, V]) IsExpired(now int64) bool {
+	expireTime := n.ExpireTime.Load()
+	return expireTime != 0 && now >= expireTime
+}
+
+// IsAlive reports whether the node is still the cache's entry for its key.
+func (n *GoffeineNode[K, V]) IsAlive() bool {
+	return !n.retired.Load()
 }
 
-func New(key string, value any, position Position) *GoffeineNode {
-	return &GoffeineNode{Key: key, Value: value, Position: position}
+// Retire marks the node as removed from the cache's map. The caller holds the node's lock.
+func (n *GoffeineNode[K, V]) Retire() {
+	n.retired.Store(true)
 }
@@ -0,0 +1,53 @@
+package goffeine
+
+import (
+	"goffeine/internal/node"
+	"iter"
+)
+
+// All returns an iterator over the live entries of the cache, in no particular order. It skips the
+// expired entries, stale ones included, and the cached failures. Iterating is not an access: it
+// neither records hits nor changes the recency or frequency of the entries. Like sync.Map.Range,
+// it does not see the cache at a single point in time, and the cache may be used while it runs.
+//
+// e.g.	for key, value := range cache.All() { ... }
+func (g *Goffeine[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		now := g.ticker.Read()
+		g.data.Range(func(_, v any) bool {
+			gnode := v.(*node.GoffeineNode[K, V])
+			value, err := gnode.Load()
+			if err != nil || gnode.IsExpired(now) {
+				return true
+			}
+			return yield(gnode.Key, value)
+		})
+	}
+}
+
+// Keys returns an iterator over the keys of the live entries of the cache, like All.
+func (g *Goffeine[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range g.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of the live entries of the cache, like All.
+func (g *Goffeine[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, value := range g.All() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Range calls f for each live entry of the cache, like All, until f returns false.
+func (g *Goffeine[K, V]) Range(f func(key K, value V) bool) {
+	g.All()(f)
+}
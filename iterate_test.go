@@ -0,0 +1,63 @@
+package goffeine_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAll(t *testing.T) {
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).Build()
+	cache.PutAll(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 3}, maps.Collect(cache.All()))
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, slices.Collect(cache.Keys()))
+	assert.ElementsMatch(t, []int{1, 2, 3}, slices.Collect(cache.Values()))
+
+	ranged := 0
+	cache.Range(func(key string, value int) bool {
+		ranged++
+		return false
+	})
+	assert.Equal(t, 1, ranged)
+	for range cache.Keys() {
+		ranged++
+		break
+	}
+	assert.Equal(t, 2, ranged)
+}
+
+func TestAllSkipsExpiredEntriesAndFailures(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).Ticker(ticker).
+		CacheLoadFailures(time.Minute).BuildLoading(goffeine.LoaderFunc[string, int](func(string) (int, error) {
+		return 0, errUnavailable
+	}))
+	cache.Put("live", 1)
+	cache.PutWithDelay("expired", 2, 1000)
+	cache.Get("failed")
+	ticker.Advance(time.Second)
+
+	assert.Equal(t, 3, cache.EstimatedSize())
+	assert.Equal(t, map[string]int{"live": 1}, maps.Collect(cache.All()))
+}
+
+func TestAllIsNotAnAccess(t *testing.T) {
+	cache := goffeine.NewBuilder[int, int]().MaximumSize(100).RecordStats().Build()
+	for i := 0; i < 50; i++ {
+		cache.Put(i, i)
+	}
+	cache.CleanUp()
+	coldest := cache.Policy().Coldest(50)
+
+	for range 10 {
+		for range cache.All() {
+		}
+	}
+	cache.CleanUp()
+	assert.Equal(t, coldest, cache.Policy().Coldest(50))
+	assert.Equal(t, goffeine.CacheStats{}, cache.Stats())
+}
@@ -0,0 +1,37 @@
+package goffeine
+
+import "context"
+
+// A CacheLoader computes the value of a key for the cache, e.g. from a database.
+type CacheLoader[K comparable, V any] interface {
+	Load(key K) (V, error)
+}
+
+// A LoaderFunc adapts an ordinary function to a CacheLoader.
+type LoaderFunc[K comparable, V any] func(key K) (V, error)
+
+func (f LoaderFunc[K, V]) Load(key K) (V, error) { return f(key) }
+
+// A BulkLoader is a CacheLoader that can also load many keys in one call, e.g. with a single query.
+// LoadAll returns the values of the keys it found; keys missing from the result are not found, and
+// only cached as such with CacheNotFound.
+type BulkLoader[K comparable, V any] interface {
+	CacheLoader[K, V]
+	LoadAll(keys []K) (map[K]V, error)
+}
+
+// A ContextLoader is a CacheLoader whose loads honour a context, so that a load nobody waits for
+// any more can be abandoned. LoadingCache.Get and GetCtx call LoadCtx, background reloads call
+// Load.
+type ContextLoader[K comparable, V any] interface {
+	CacheLoader[K, V]
+	LoadCtx(ctx context.Context, key K) (V, error)
+}
+
+// A ContextLoaderFunc adapts an ordinary function to a ContextLoader. Its Load runs with
+// context.Background().
+type ContextLoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+func (f ContextLoaderFunc[K, V]) Load(key K) (V, error) { return f(context.Background(), key) }
+
+func (f ContextLoaderFunc[K, V]) LoadCtx(ctx context.Context, key K) (V, error) { return f(ctx, key) }
@@ -0,0 +1,299 @@
+package goffeine
+
+import (
+	"context"
+	"errors"
+	"goffeine/internal/node"
+	"sync"
+	"time"
+)
+
+// A LoadingCache is a Goffeine that computes missing values with its CacheLoader. Concurrent misses
+// for the same key share a single load, and a failed load is returned to every waiting caller. It
+// is not cached unless the cache was built with CacheLoadFailures, or CacheNotFound for
+// ErrNotFound. A load does not replace a value put, nor bring back an entry invalidated, while it
+// ran: its callers get what it loaded, but the cache keeps the newer write.
+type LoadingCache[K comparable, V any] struct {
+	*Goffeine[K, V]
+	// lock guards loads. A load is published to the cache, if at all, before it leaves loads.
+	lock  sync.Mutex
+	loads map[K]*load[V]
+}
+
+// A load is a call to the CacheLoader in flight, shared by the callers that missed the same key.
+// Its context is cancelled once every one of them has given up waiting.
+type load[V any] struct {
+	done    chan struct{}
+	value   V
+	err     error
+	waiters int // guarded by the LoadingCache's lock
+	cancel  context.CancelFunc
+	writes  uint64 // the writes of the key's stripe when the load started
+}
+
+// Get returns the value of key, loading it on a miss.
+func (c *LoadingCache[K, V]) Get(key K) (V, error) {
+	return c.GetCtx(context.Background(), key)
+}
+
+// GetCtx returns the value of key, loading it on a miss. The caller waits for the load until ctx
+// is done, and then gets ctx's error. The load carries on for the other callers waiting for it,
+// and its context is cancelled when none is left. A cached failure of the key is returned without
+// loading.
+func (c *LoadingCache[K, V]) GetCtx(ctx context.Context, key K) (V, error) {
+	value, _, err := c.getCtx(ctx, key)
+	return value, err
+}
+
+// GetWithStale is Get, and also reports whether the value is stale: it expired, and is served
+// during the grace period of ServeStale while it is reloaded in the background.
+func (c *LoadingCache[K, V]) GetWithStale(key K) (value V, stale bool, err error) {
+	return c.getCtx(context.Background(), key)
+}
+
+func (c *LoadingCache[K, V]) getCtx(ctx context.Context, key K) (V, bool, error) {
+	value, ok, stale, err := c.getEntry(key)
+	if ok {
+		if err != nil {
+			c.stats.recordNegativeHits(1)
+		} else {
+			c.stats.recordHits(1)
+		}
+		return value, stale, err
+	}
+	c.stats.recordMisses(1)
+	return c.loadMiss(ctx, key)
+}
+
+// loadMiss returns the value of key, which missed in the cache, by joining its load in flight or
+// starting one. It does not record the miss.
+func (c *LoadingCache[K, V]) loadMiss(ctx context.Context, key K) (V, bool, error) {
+	if err := ctx.Err(); err != nil {
+		var zero V
+		return zero, false, err
+	}
+
+	c.lock.Lock()
+	// peek does not run maintenance, whose removal listener may call Get and take the lock again
+	if value, ok, stale, err := c.peek(key); ok {
+		// loaded while this caller was waiting for the lock
+		c.lock.Unlock()
+		return value, stale, err
+	}
+	l, ok := c.loads[key]
+	if !ok {
+		// the load outlives the caller that starts it, but keeps the values of its context
+		loadCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		l = &load[V]{done: make(chan struct{}), cancel: cancel}
+		c.loads[key] = l
+		go c.load(loadCtx, key, l)
+	}
+	l.waiters++
+	c.lock.Unlock()
+
+	select {
+	case <-l.done:
+		return l.value, false, l.err
+	case <-ctx.Done():
+		var zero V
+		c.lock.Lock()
+		l.waiters--
+		if l.waiters == 0 {
+			l.cancel()
+			if c.loads[key] == l {
+				// a later miss starts a fresh load instead of joining the cancelled one
+				delete(c.loads, key)
+			}
+		}
+		c.lock.Unlock()
+		return zero, false, ctx.Err()
+	}
+}
+
+// load calls the loader for key and publishes the result to the cache and to the waiting callers.
+// The result is in the cache, and usually in the write buffer, before the callers are released,
+// but maintenance runs only after, as its removal listener may wait for a load that waits for this
+// one.
+func (c *LoadingCache[K, V]) load(ctx context.Context, key K, l *load[V]) {
+	defer func() {
+		task, replaced := c.publish(ctx, key, l)
+		if task != nil && c.offerWrite(task) {
+			task = nil
+		}
+		c.lock.Lock()
+		if c.loads[key] == l {
+			delete(c.loads, key)
+		}
+		c.lock.Unlock()
+		l.cancel()
+		close(l.done)
+		c.buffer(task, replaced)
+		c.scheduleDrainBuffers()
+	}()
+	l.writes = c.keyWrites(key)
+	start := c.ticker.Read()
+	if loader, ok := c.loader.(ContextLoader[K, V]); ok {
+		l.value, l.err = loader.LoadCtx(ctx, key)
+	} else {
+		l.value, l.err = c.loader.Load(key)
+	}
+	c.stats.recordLoad(l.err, time.Duration(c.ticker.Read()-start))
+}
+
+// publish writes the result of l to data like publishLoaded. A failure is written only if it is
+// cached.
+func (c *LoadingCache[K, V]) publish(ctx context.Context, key K, l *load[V]) (func(), *removal[K, V]) {
+	expire := c.expireAfterWrite
+	if l.err != nil {
+		if ctx.Err() != nil {
+			// an abandoned load is not a failure of the key
+			return nil, nil
+		}
+		if expire = c.expireFailure(l.err); expire == nil {
+			return nil, nil
+		}
+	}
+	return c.publishLoaded(key, l.value, l.err, &l.writes, expire)
+}
+
+// publishLoaded writes what was loaded for key to data, unless key was written or removed since
+// its stripe counted writes, or holds a live value, as those are newer than the load. It returns
+// like write, and an expired value it replaces is removed as Expired. writes is moved past the
+// write, so that the other keys of a bulk load in the same stripe do not take it for a newer one.
+func (c *LoadingCache[K, V]) publishLoaded(key K, value V, err error, writes *uint64, expire func(gnode *node.GoffeineNode[K, V], now int64, created bool)) (func(), *removal[K, V]) {
+	lock := c.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+	if lock.writes != *writes {
+		return nil, nil
+	}
+	defer func() { *writes = lock.writes }()
+	now := c.ticker.Read()
+	expired := false
+	if v, ok := c.data.Load(key); ok {
+		gnode := v.(*node.GoffeineNode[K, V])
+		if _, oldErr := gnode.Load(); oldErr == nil {
+			if !gnode.IsExpired(now) {
+				return nil, nil
+			}
+			expired = true
+		}
+	}
+	task, r := c.write(key, value, err, now, func(gnode *node.GoffeineNode[K, V], now int64, created bool) {
+		// a value replacing an expired one is as good as created
+		expire(gnode, now, created || expired)
+	})
+	if r != nil && expired {
+		r.cause = Expired
+	}
+	return task, r
+}
+
+// GetAll returns the values of keys, loading the missing ones. A BulkLoader loads all the misses
+// with one LoadAll call, any other loader loads them one by one like Get. The keys that are not
+// found are left out of the result. A key repeated in keys is looked up and loaded once.
+func (c *LoadingCache[K, V]) GetAll(keys []K) (map[K]V, error) {
+	keys = distinct(keys)
+	entries, failures := c.getAll(keys)
+	c.stats.recordHits(len(entries))
+	c.stats.recordNegativeHits(len(failures))
+	c.stats.recordMisses(len(keys) - len(entries) - len(failures))
+	for _, err := range failures {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	var missing []K
+	for _, key := range keys {
+		_, found := entries[key]
+		_, failed := failures[key]
+		if !found && !failed {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return entries, nil
+	}
+
+	bulkLoader, ok := c.loader.(BulkLoader[K, V])
+	if !ok {
+		for _, key := range missing {
+			// the miss is recorded already
+			value, _, err := c.loadMiss(context.Background(), key)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			entries[key] = value
+		}
+		return entries, nil
+	}
+
+	// the writes of each stripe of the keys, counted before the load
+	writes := make(map[*stripedLock]*uint64)
+	stripeWrites := func(key K) *uint64 {
+		lock := c.keyLock(key)
+		if _, ok := writes[lock]; !ok {
+			count := c.keyWrites(key)
+			writes[lock] = &count
+		}
+		return writes[lock]
+	}
+	for _, key := range missing {
+		stripeWrites(key)
+	}
+	start := c.ticker.Read()
+	loaded, err := bulkLoader.LoadAll(missing)
+	c.stats.recordLoad(err, time.Duration(c.ticker.Read()-start))
+	defer c.scheduleDrainBuffers()
+	if err != nil {
+		for _, key := range missing {
+			c.publishFailure(key, err, stripeWrites(key))
+		}
+		return nil, err
+	}
+	for key, value := range loaded {
+		// a key that was not asked for is counted only now, unless it shares a stripe with one
+		c.buffer(c.publishLoaded(key, value, nil, stripeWrites(key), c.expireAfterWrite))
+	}
+	for _, key := range missing {
+		if value, ok := loaded[key]; ok {
+			entries[key] = value
+		} else {
+			c.publishFailure(key, ErrNotFound, stripeWrites(key))
+		}
+	}
+	return entries, nil
+}
+
+// publishFailure caches the failure of a bulk load of key like publishLoaded, if failures like err
+// are cached.
+func (c *LoadingCache[K, V]) publishFailure(key K, err error, writes *uint64) {
+	if expire := c.expireFailure(err); expire != nil {
+		var zero V
+		c.buffer(c.publishLoaded(key, zero, err, writes, expire))
+	}
+}
+
+// buffer delivers the value replaced by a publish and buffers its write, if there is one.
+func (c *LoadingCache[K, V]) buffer(task func(), replaced *removal[K, V]) {
+	c.deliver(replaced)
+	if task != nil {
+		c.bufferWrite(task)
+	}
+}
+
+// distinct returns keys without the repeated ones, in the order they first appear.
+func distinct[K comparable](keys []K) []K {
+	seen := make(map[K]struct{}, len(keys))
+	unique := keys[:0:0]
+	for _, key := range keys {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,196 @@
+package goffeine_test
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadingCacheLoadsOnMiss(t *testing.T) {
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[int, string]().MaximumSize(100).BuildLoading(
+		goffeine.LoaderFunc[int, string](func(key int) (string, error) {
+			loads.Add(1)
+			return strconv.Itoa(key), nil
+		}))
+
+	v, err := cache.Get(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", v)
+	v, err = cache.Get(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", v)
+	assert.Equal(t, int32(1), loads.Load())
+
+	cache.Put(2, "two")
+	v, _ = cache.Get(2)
+	assert.Equal(t, "two", v)
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestLoadingCacheSharesConcurrentLoads(t *testing.T) {
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).BuildLoading(
+		goffeine.LoaderFunc[string, int](func(key string) (int, error) {
+			loads.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return 42, nil
+		}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := cache.Get("answer")
+			assert.Nil(t, err)
+			assert.Equal(t, 42, v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestLoadingCacheDoesNotCacheErrors(t *testing.T) {
+	var loads atomic.Int32
+	failure := errors.New("backend is down")
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).BuildLoading(
+		goffeine.LoaderFunc[string, int](func(key string) (int, error) {
+			if loads.Add(1) == 1 {
+				time.Sleep(100 * time.Millisecond)
+				return 0, failure
+			}
+			return 1, nil
+		}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Get("a")
+			assert.Same(t, failure, err)
+		}()
+	}
+	wg.Wait()
+
+	v, err := cache.Get("a")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, int32(2), loads.Load())
+}
+
+type RowLoader struct {
+	loads    atomic.Int32
+	bulks    atomic.Int32
+	requests [][]int
+}
+
+func (l *RowLoader) Load(key int) (string, error) {
+	l.loads.Add(1)
+	return "row" + strconv.Itoa(key), nil
+}
+
+func (l *RowLoader) LoadAll(keys []int) (map[int]string, error) {
+	l.bulks.Add(1)
+	l.requests = append(l.requests, keys)
+	rows := make(map[int]string, len(keys))
+	for _, key := range keys {
+		if key%10 != 0 { // multiples of ten are missing in the backend
+			rows[key] = "row" + strconv.Itoa(key)
+		}
+	}
+	return rows, nil
+}
+
+func TestGetAllAndPutAll(t *testing.T) {
+	cache := goffeine.NewBuilder[int, string]().MaximumSize(100).Build()
+	cache.PutAll(map[int]string{1: "a", 2: "b", 3: "c"})
+
+	entries := cache.GetAll([]int{1, 3, 4})
+	assert.Equal(t, map[int]string{1: "a", 3: "c"}, entries)
+}
+
+func TestLoadingCacheGetAllWithBulkLoader(t *testing.T) {
+	loader := &RowLoader{}
+	cache := goffeine.NewBuilder[int, string]().MaximumSize(100).BuildLoading(loader)
+	cache.Put(1, "cached")
+
+	entries, err := cache.GetAll([]int{1, 2, 3, 10})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]string{1: "cached", 2: "row2", 3: "row3"}, entries)
+	assert.Equal(t, int32(1), loader.bulks.Load())
+	assert.Equal(t, [][]int{{2, 3, 10}}, loader.requests)
+	assert.Equal(t, int32(0), loader.loads.Load())
+
+	entries, err = cache.GetAll([]int{2, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]string{2: "row2", 3: "row3"}, entries)
+	assert.Equal(t, int32(1), loader.bulks.Load())
+}
+
+func TestLoadingCacheGetAllWithoutBulkLoader(t *testing.T) {
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[int, string]().MaximumSize(100).BuildLoading(
+		goffeine.LoaderFunc[int, string](func(key int) (string, error) {
+			loads.Add(1)
+			return strconv.Itoa(key), nil
+		}))
+
+	entries, err := cache.GetAll([]int{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]string{1: "1", 2: "2"}, entries)
+	assert.Equal(t, int32(2), loads.Load())
+}
+
+// pausedLoader returns a loader that tells started when it is called, and returns the key's
+// length once release is closed.
+func pausedLoader(started chan<- struct{}, release <-chan struct{}) goffeine.LoaderFunc[string, int] {
+	return func(key string) (int, error) {
+		started <- struct{}{}
+		<-release
+		return len(key), nil
+	}
+}
+
+func TestLoadDoesNotOverwriteAConcurrentPut(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).BuildLoading(pausedLoader(started, release))
+
+	loaded := make(chan int)
+	go func() {
+		v, _ := cache.Get("key")
+		loaded <- v
+	}()
+	<-started
+	cache.Put("key", 42)
+	close(release)
+
+	// the caller gets what it loaded, but the cache keeps the newer value
+	assert.Equal(t, 3, <-loaded)
+	v, ok := cache.Goffeine.Get("key")
+	assert.Equal(t, []any{42, true}, []any{v, ok})
+}
+
+func TestLoadDoesNotUndoAConcurrentInvalidate(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).BuildLoading(pausedLoader(started, release))
+
+	loaded := make(chan int)
+	go func() {
+		v, _ := cache.Get("key")
+		loaded <- v
+	}()
+	<-started
+	cache.Invalidate("key")
+	close(release)
+
+	assert.Equal(t, 3, <-loaded)
+	_, ok := cache.Goffeine.Get("key")
+	assert.False(t, ok)
+}
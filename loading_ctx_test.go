@@ -0,0 +1,124 @@
+package goffeine_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingLoader returns a loader that blocks until release is closed or its context is done,
+// and reports its context's error on cancelled.
+func blockingLoader(release chan struct{}, cancelled chan error) goffeine.ContextLoaderFunc[string, int] {
+	return func(ctx context.Context, key string) (int, error) {
+		select {
+		case <-release:
+			return len(key), nil
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func TestGetCtxDeadlineBoundsTheWait(t *testing.T) {
+	release, cancelled := make(chan struct{}), make(chan error, 1)
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).BuildLoading(blockingLoader(release, cancelled))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := cache.GetCtx(ctx, "slow")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the only waiter gave up, so the load is cancelled
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+}
+
+func TestGetCtxKeepsLoadingForTheRemainingWaiters(t *testing.T) {
+	release, cancelled := make(chan struct{}), make(chan error, 1)
+	var loads atomic.Int32
+	loader := blockingLoader(release, cancelled)
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).BuildLoading(
+		goffeine.ContextLoaderFunc[string, int](func(ctx context.Context, key string) (int, error) {
+			loads.Add(1)
+			return loader(ctx, key)
+		}))
+
+	impatient, cancel := context.WithCancel(context.Background())
+	gaveUp := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(gaveUp)
+		_, err := cache.GetCtx(impatient, "key")
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+	go func() {
+		defer wg.Done()
+		v, err := cache.GetCtx(context.Background(), "key")
+		assert.Nil(t, err)
+		assert.Equal(t, 3, v)
+	}()
+	for cache.Waiters("key") < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-gaveUp
+	assert.Equal(t, 1, cache.Waiters("key"))
+	close(release)
+	wg.Wait()
+
+	assert.Empty(t, cancelled)
+	assert.Equal(t, int32(1), loads.Load())
+	v, ok := cache.Goffeine.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+}
+
+func TestGetCtxStartsAFreshLoadAfterACancelledOne(t *testing.T) {
+	release, cancelled := make(chan struct{}), make(chan error, 2)
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).BuildLoading(blockingLoader(release, cancelled))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cache.GetCtx(ctx, "key")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, cancelled, "a caller that is done already does not start a load")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = cache.GetCtx(ctx, "key")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	<-cancelled
+
+	close(release)
+	v, err := cache.GetCtx(context.Background(), "key")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, v)
+}
+
+func TestGetCtxPassesTheCallerValues(t *testing.T) {
+	type requestID struct{}
+	cache := goffeine.NewBuilder[string, string]().MaximumSize(100).BuildLoading(
+		goffeine.ContextLoaderFunc[string, string](func(ctx context.Context, key string) (string, error) {
+			return ctx.Value(requestID{}).(string), nil
+		}))
+
+	ctx := context.WithValue(context.Background(), requestID{}, "r-1")
+	v, err := cache.GetCtx(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, "r-1", v)
+
+	// a hit does not care about the context
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	v, err = cache.GetCtx(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, "r-1", v)
+}
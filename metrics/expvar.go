@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"expvar"
+	"goffeine"
+)
+
+// Var is the JSON document published for a cache on /debug/vars.
+type Var struct {
+	Size         int    `json:"size"`
+	WeightedSize int    `json:"weightedSize"`
+	Stats        Stats  `json:"stats"`
+	Config       Config `json:"config"`
+}
+
+type Stats struct {
+	HitCount         uint64  `json:"hitCount"`
+	MissCount        uint64  `json:"missCount"`
+	NegativeHitCount uint64  `json:"negativeHitCount"`
+	HitRate          float64 `json:"hitRate"`
+	MissRate         float64 `json:"missRate"`
+	LoadSuccessCount uint64  `json:"loadSuccessCount"`
+	LoadFailureCount uint64  `json:"loadFailureCount"`
+	TotalLoadTimeMs  int64   `json:"totalLoadTimeMilliseconds"`
+	EvictionCount    uint64  `json:"evictionCount"`
+	EvictionWeight   uint64  `json:"evictionWeight"`
+}
+
+type Config struct {
+	MaximumSize          int   `json:"maximumSize"`
+	MaximumWeight        int   `json:"maximumWeight"`
+	WindowMaximumSize    int   `json:"windowMaximumSize"`
+	ProbationMaximumSize int   `json:"probationMaximumSize"`
+	ProtectedMaximumSize int   `json:"protectedMaximumSize"`
+	ExpireMilliseconds   int64 `json:"expireMilliseconds"`
+	AccessMilliseconds   int64 `json:"accessMilliseconds"`
+	RefreshMilliseconds  int64 `json:"refreshMilliseconds"`
+}
+
+// Publish publishes the cache's statistics and configuration through expvar under name, so they
+// show up as JSON on /debug/vars. Like expvar.Publish, it panics if name is already in use.
+func Publish(name string, cache Source) {
+	expvar.Publish(name, expvar.Func(func() any { return NewVar(cache) }))
+}
+
+// NewVar takes a snapshot of the cache as it is published by Publish.
+func NewVar(cache Source) Var {
+	return Var{
+		Size:         cache.EstimatedSize(),
+		WeightedSize: cache.WeightedSize(),
+		Stats:        newStats(cache.Stats()),
+		Config: Config{
+			MaximumSize:          cache.MaximumSize(),
+			MaximumWeight:        cache.MaximumWeight(),
+			WindowMaximumSize:    cache.WindowMaximumSize(),
+			ProbationMaximumSize: cache.ProbationMaximumSize(),
+			ProtectedMaximumSize: cache.ProtectedMaximumSize(),
+			ExpireMilliseconds:   cache.ExpireMilliseconds(),
+			AccessMilliseconds:   cache.AccessMilliseconds(),
+			RefreshMilliseconds:  cache.RefreshMilliseconds(),
+		},
+	}
+}
+
+func newStats(stats goffeine.CacheStats) Stats {
+	return Stats{
+		HitCount:         stats.HitCount(),
+		MissCount:        stats.MissCount(),
+		NegativeHitCount: stats.NegativeHitCount(),
+		HitRate:          stats.HitRate(),
+		MissRate:         stats.MissRate(),
+		LoadSuccessCount: stats.LoadSuccessCount(),
+		LoadFailureCount: stats.LoadFailureCount(),
+		TotalLoadTimeMs:  stats.TotalLoadTime().Milliseconds(),
+		EvictionCount:    stats.EvictionCount(),
+		EvictionWeight:   stats.EvictionWeight(),
+	}
+}
@@ -0,0 +1,57 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"goffeine/metrics"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runs numbers the runs of TestPublish, as expvar does not let a name be published twice.
+var runs atomic.Int32
+
+func TestPublish(t *testing.T) {
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Minute, 5).
+		RefreshAfterWrite(time.Minute, 1).RecordStats().Build()
+	name := fmt.Sprintf("goffeine.test.users.%d", runs.Add(1))
+	metrics.Publish(name, cache)
+	cache.Put("a", 1)
+	cache.Get("a")
+	cache.Get("b")
+
+	published := expvar.Get(name)
+	assert.NotNil(t, published)
+	var v metrics.Var
+	assert.Nil(t, json.Unmarshal([]byte(published.String()), &v))
+
+	assert.Equal(t, 1, v.Size)
+	assert.Equal(t, 1, v.WeightedSize)
+	assert.Equal(t, uint64(1), v.Stats.HitCount)
+	assert.Equal(t, uint64(1), v.Stats.MissCount)
+	assert.Equal(t, 0.5, v.Stats.HitRate)
+	assert.Equal(t, metrics.Config{
+		MaximumSize:          100,
+		WindowMaximumSize:    1,
+		ProbationMaximumSize: 19,
+		ProtectedMaximumSize: 80,
+		ExpireMilliseconds:   (5 * time.Minute).Milliseconds(),
+		RefreshMilliseconds:  time.Minute.Milliseconds(),
+	}, v.Config)
+	assert.Contains(t, published.String(), `"maximumSize":100`)
+}
+
+func TestNewVarOfAWeightedCache(t *testing.T) {
+	cache := goffeine.NewBuilder[string, string]().MaximumWeight(1000).
+		Weigher(func(key string, value string) uint32 { return uint32(len(value)) }).Build()
+	cache.Put("a", "four")
+
+	v := metrics.NewVar(cache)
+	assert.Equal(t, 4, v.WeightedSize)
+	assert.Equal(t, 0, v.Config.MaximumSize)
+	assert.Equal(t, 1000, v.Config.MaximumWeight)
+}
@@ -0,0 +1,172 @@
+// Package metrics exports the statistics of goffeine caches in the Prometheus text exposition
+// format, or through expvar, with nothing but the standard library.
+//
+// e.g.	handler := metrics.NewHandler()
+//
+//	handler.Register("users", usersCache)
+//	http.Handle("/metrics", handler)
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"goffeine"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// A Source is a cache that can be exported. Every *goffeine.Goffeine is a Source, whatever its key
+// and value types.
+type Source interface {
+	Stats() goffeine.CacheStats
+	EstimatedSize() int
+	WeightedSize() int
+	MaximumSize() int
+	MaximumWeight() int
+	WindowSize() int
+	WindowMaximumSize() int
+	ProbationSize() int
+	ProbationMaximumSize() int
+	ProtectedSize() int
+	ProtectedMaximumSize() int
+	ExpireMilliseconds() int64
+	AccessMilliseconds() int64
+	RefreshMilliseconds() int64
+}
+
+// A Handler is an http.Handler that writes the metrics of the registered caches.
+type Handler struct {
+	lock    sync.RWMutex
+	sources map[string]Source
+}
+
+func NewHandler() *Handler {
+	return &Handler{sources: make(map[string]Source)}
+}
+
+// Register exports the cache under name, which becomes the value of the "cache" label. A cache
+// already registered under the same name is replaced.
+func (h *Handler) Register(name string, cache Source) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.sources[name] = cache
+}
+
+// Unregister stops exporting the cache registered under name.
+func (h *Handler) Unregister(name string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.sources, name)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	out := bufio.NewWriter(w)
+	h.write(out)
+	out.Flush()
+}
+
+// A sample is one line of a metric, its labels are written after the cache label.
+type sample struct {
+	labels string
+	value  float64
+}
+
+// A metric is a family of samples with its help and type lines.
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	samples func(cache Source) []sample
+}
+
+func counter(name string, help string, value func(stats goffeine.CacheStats) float64) metric {
+	return metric{name, help, "counter", func(cache Source) []sample {
+		return []sample{{"", value(cache.Stats())}}
+	}}
+}
+
+func gauge(name string, help string, value func(cache Source) float64) metric {
+	return metric{name, help, "gauge", func(cache Source) []sample {
+		return []sample{{"", value(cache)}}
+	}}
+}
+
+func regionGauge(name string, help string, window, probation, protected func(cache Source) int) metric {
+	return metric{name, help, "gauge", func(cache Source) []sample {
+		return []sample{
+			{`,region="window"`, float64(window(cache))},
+			{`,region="probation"`, float64(probation(cache))},
+			{`,region="protected"`, float64(protected(cache))},
+		}
+	}}
+}
+
+var metrics = []metric{
+	counter("goffeine_cache_hits_total", "Number of lookups that found their entry.",
+		func(s goffeine.CacheStats) float64 { return float64(s.HitCount()) }),
+	counter("goffeine_cache_misses_total", "Number of lookups that did not find their entry.",
+		func(s goffeine.CacheStats) float64 { return float64(s.MissCount()) }),
+	counter("goffeine_cache_negative_hits_total", "Number of lookups that found a cached load failure.",
+		func(s goffeine.CacheStats) float64 { return float64(s.NegativeHitCount()) }),
+	counter("goffeine_cache_load_successes_total", "Number of loads that returned a value.",
+		func(s goffeine.CacheStats) float64 { return float64(s.LoadSuccessCount()) }),
+	counter("goffeine_cache_load_failures_total", "Number of loads that returned an error.",
+		func(s goffeine.CacheStats) float64 { return float64(s.LoadFailureCount()) }),
+	counter("goffeine_cache_load_duration_seconds_total", "Time spent loading values.",
+		func(s goffeine.CacheStats) float64 { return s.TotalLoadTime().Seconds() }),
+	counter("goffeine_cache_evictions_total", "Number of entries evicted because of size or expiration.",
+		func(s goffeine.CacheStats) float64 { return float64(s.EvictionCount()) }),
+	counter("goffeine_cache_eviction_weight_total", "Total weight of the evicted entries.",
+		func(s goffeine.CacheStats) float64 { return float64(s.EvictionWeight()) }),
+	gauge("goffeine_cache_size", "Number of entries in the cache.",
+		func(c Source) float64 { return float64(c.EstimatedSize()) }),
+	gauge("goffeine_cache_weight", "Total weight of the entries in the cache.",
+		func(c Source) float64 { return float64(c.WeightedSize()) }),
+	gauge("goffeine_cache_maximum_size", "Maximum number of entries in the cache, 0 if it is bounded by weight.",
+		func(c Source) float64 { return float64(c.MaximumSize()) }),
+	gauge("goffeine_cache_maximum_weight", "Maximum total weight of the entries in the cache, 0 if it is bounded by size.",
+		func(c Source) float64 { return float64(c.MaximumWeight()) }),
+	regionGauge("goffeine_cache_region_size", "Number of entries in each region of the cache.",
+		Source.WindowSize, Source.ProbationSize, Source.ProtectedSize),
+	regionGauge("goffeine_cache_region_maximum_size", "Maximum size of each region of the cache, in weight if it is bounded by weight.",
+		Source.WindowMaximumSize, Source.ProbationMaximumSize, Source.ProtectedMaximumSize),
+}
+
+// write writes the metrics of every registered cache in the Prometheus text format, with the
+// caches sorted by name.
+func (h *Handler) write(w io.Writer) {
+	h.lock.RLock()
+	names := make([]string, 0, len(h.sources))
+	for name := range h.sources {
+		names = append(names, name)
+	}
+	sources := make([]Source, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		sources[i] = h.sources[name]
+	}
+	h.lock.RUnlock()
+
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+		for i, source := range sources {
+			for _, s := range m.samples(source) {
+				fmt.Fprintf(w, "%s{cache=\"%s\"%s} %v\n", m.name, escape(names[i]), s.labels, s.value)
+			}
+		}
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape escapes a label value as the exposition format requires.
+func escape(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,83 @@
+package metrics_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"goffeine/metrics"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, handler *metrics.Handler) string {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	body, err := io.ReadAll(recorder.Body)
+	assert.Nil(t, err)
+	return string(body)
+}
+
+func TestHandler(t *testing.T) {
+	users := goffeine.NewBuilder[string, int]().MaximumSize(100).RecordStats().Build()
+	for i := 0; i < 150; i++ {
+		users.Put(strconv.Itoa(i), i)
+	}
+	users.Get("149")
+	users.Get("missing")
+	sessions := goffeine.NewBuilder[int, string]().MaximumSize(10).Build()
+
+	handler := metrics.NewHandler()
+	handler.Register("users", users)
+	handler.Register("sessions", sessions)
+	body := scrape(t, handler)
+
+	for _, line := range []string{
+		"# HELP goffeine_cache_hits_total Number of lookups that found their entry.",
+		"# TYPE goffeine_cache_hits_total counter",
+		`goffeine_cache_hits_total{cache="users"} 1`,
+		`goffeine_cache_misses_total{cache="users"} 1`,
+		`goffeine_cache_evictions_total{cache="users"} 50`,
+		`goffeine_cache_eviction_weight_total{cache="users"} 50`,
+		"# TYPE goffeine_cache_size gauge",
+		`goffeine_cache_size{cache="users"} 100`,
+		`goffeine_cache_weight{cache="users"} 100`,
+		`goffeine_cache_maximum_size{cache="users"} 100`,
+		`goffeine_cache_region_size{cache="users",region="window"} 1`,
+		`goffeine_cache_region_size{cache="users",region="probation"} 99`,
+		`goffeine_cache_region_size{cache="users",region="protected"} 0`,
+		`goffeine_cache_region_maximum_size{cache="users",region="protected"} 80`,
+		`goffeine_cache_size{cache="sessions"} 0`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	// caches are sorted by name
+	assert.Less(t, strings.Index(body, `{cache="sessions"}`), strings.Index(body, `{cache="users"}`))
+
+	handler.Unregister("sessions")
+	assert.NotContains(t, scrape(t, handler), "sessions")
+}
+
+func TestHandlerExportsTheMaximumWeight(t *testing.T) {
+	weighted := goffeine.NewBuilder[string, string]().MaximumWeight(1000).
+		Weigher(func(key string, value string) uint32 { return uint32(len(value)) }).Build()
+	handler := metrics.NewHandler()
+	handler.Register("pages", weighted)
+	body := scrape(t, handler)
+
+	for _, line := range []string{
+		`goffeine_cache_maximum_size{cache="pages"} 0`,
+		`goffeine_cache_maximum_weight{cache="pages"} 1000`,
+		`goffeine_cache_region_maximum_size{cache="pages",region="protected"} 792`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
+
+func TestHandlerEscapesCacheNames(t *testing.T) {
+	handler := metrics.NewHandler()
+	handler.Register("a \"quoted\"\\name\n", goffeine.NewBuilder[string, int]().Build())
+	assert.Contains(t, scrape(t, handler), `goffeine_cache_size{cache="a \"quoted\"\\name\n"} 0`)
+}
@@ -0,0 +1,35 @@
+package goffeine
+
+import (
+	"errors"
+	"goffeine/internal/node"
+	"time"
+)
+
+// ErrNotFound is returned by a CacheLoader that found no value for the key, e.g. for a missing
+// row. It may be wrapped. A LoadingCache built with CacheNotFound caches it like a value, so the
+// same key is not looked up again on every Get.
+var ErrNotFound = errors.New("goffeine: not found")
+
+// negativeMilliseconds returns how long a load that failed with err is cached, 0 if it is not.
+func (g *Goffeine[K, V]) negativeMilliseconds(err error) int64 {
+	if errors.Is(err, ErrNotFound) {
+		return g.notFoundMilliseconds
+	}
+	return g.failureMilliseconds
+}
+
+// expireFailure returns how to set the deadline of a cached failure like err, nil if it is not
+// cached. The entry weighs negativeWeight and expires after its own time to live, whatever the
+// expiration settings of the values are. Reads do not extend it, and a Put of a value replaces it.
+// A failure never replaces a live value.
+func (g *Goffeine[K, V]) expireFailure(err error) func(gnode *node.GoffeineNode[K, V], now int64, created bool) {
+	delayMilliseconds := g.negativeMilliseconds(err)
+	if delayMilliseconds <= 0 {
+		return nil
+	}
+	return func(gnode *node.GoffeineNode[K, V], now int64, _ bool) {
+		// unlike scheduleExpiration, not capped by ExpireAfterAccess
+		g.setVariableExpireTime(gnode, now, time.Duration(delayMilliseconds)*time.Millisecond)
+	}
+}
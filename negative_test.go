@@ -0,0 +1,236 @@
+package goffeine_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// failingLoader counts its loads and fails every one of them with err.
+func failingLoader(loads *atomic.Int32, err error) goffeine.LoaderFunc[string, int] {
+	return func(key string) (int, error) {
+		loads.Add(1)
+		return 0, err
+	}
+}
+
+func TestLoadingCacheCachesFailures(t *testing.T) {
+	ticker := &FakeTicker{}
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Minute, 1).
+		CacheLoadFailures(time.Second).Ticker(ticker).BuildLoading(failingLoader(&loads, errUnavailable))
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.Get("key")
+		assert.ErrorIs(t, err, errUnavailable)
+	}
+	assert.Equal(t, int32(1), loads.Load())
+
+	// the failure expires after its own time to live
+	ticker.Advance(time.Second)
+	_, err := cache.Get("key")
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, int32(2), loads.Load())
+}
+
+func TestFailuresOutliveTheAccessExpiration(t *testing.T) {
+	ticker := &FakeTicker{}
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterAccess(time.Second).
+		CacheLoadFailures(time.Minute).Ticker(ticker).BuildLoading(failingLoader(&loads, errUnavailable))
+
+	cache.Get("key")
+	ticker.Advance(10 * time.Second)
+	cache.CleanUp()
+	_, err := cache.Get("key")
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, int32(1), loads.Load())
+
+	ticker.Advance(time.Minute)
+	cache.Get("key")
+	assert.Equal(t, int32(2), loads.Load())
+}
+
+func TestLoadingCacheCachesNotFoundOnlyWithCacheNotFound(t *testing.T) {
+	notFound := fmt.Errorf("user 7: %w", goffeine.ErrNotFound)
+
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).
+		CacheLoadFailures(time.Minute).BuildLoading(failingLoader(&loads, notFound))
+	cache.Get("7")
+	cache.Get("7")
+	assert.Equal(t, int32(2), loads.Load())
+
+	loads.Store(0)
+	cache = goffeine.NewBuilder[string, int]().MaximumSize(100).
+		CacheNotFound(time.Minute).BuildLoading(failingLoader(&loads, notFound))
+	_, err := cache.Get("7")
+	assert.ErrorIs(t, err, goffeine.ErrNotFound)
+	_, err = cache.Get("7")
+	assert.Equal(t, notFound, err)
+	assert.Equal(t, int32(1), loads.Load())
+
+	// other failures are not cached by CacheNotFound
+	loads.Store(0)
+	cache = goffeine.NewBuilder[string, int]().MaximumSize(100).
+		CacheNotFound(time.Minute).BuildLoading(failingLoader(&loads, errUnavailable))
+	cache.Get("7")
+	cache.Get("7")
+	assert.Equal(t, int32(2), loads.Load())
+}
+
+func TestCachedFailuresAreNotValues(t *testing.T) {
+	var removed []string
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).CacheLoadFailures(time.Minute).
+		RemovalListener(func(key string, value int, cause goffeine.RemovalCause) {
+			removed = append(removed, fmt.Sprintf("%s=%d %s", key, value, cause))
+		}).BuildLoading(failingLoader(&loads, errUnavailable))
+
+	cache.Get("a")
+	cache.Get("b")
+	assert.Equal(t, 2, cache.EstimatedSize())
+	_, ok := cache.Goffeine.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, map[string]int{}, cache.Goffeine.GetAll([]string{"a", "b"}))
+	assert.Empty(t, cache.Policy().Hottest(10))
+
+	// a value replaces the failure
+	cache.Put("a", 1)
+	v, err := cache.Get("a")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	cache.InvalidateIf(func(string, int) bool { return true })
+	assert.Equal(t, 1, cache.EstimatedSize())
+	cache.InvalidateAll()
+	assert.Equal(t, 0, cache.EstimatedSize())
+	assert.Equal(t, []string{"a=1 Explicit"}, removed)
+}
+
+func TestCachedFailuresHaveTheirOwnWeight(t *testing.T) {
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumWeight(1000).
+		Weigher(func(string, int) uint32 { return 100 }).
+		CacheLoadFailures(time.Minute).NegativeWeight(2).BuildLoading(failingLoader(&loads, errUnavailable))
+
+	cache.Put("value", 1)
+	cache.Get("failure")
+	assert.Equal(t, 102, cache.WeightedSize())
+}
+
+func TestStatsNegativeHits(t *testing.T) {
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).RecordStats().
+		CacheLoadFailures(time.Minute).BuildLoading(failingLoader(&loads, errUnavailable))
+
+	cache.Get("key")
+	cache.Get("key")
+	cache.Get("key")
+	stats := cache.Stats()
+	assert.Equal(t, uint64(0), stats.HitCount())
+	assert.Equal(t, uint64(1), stats.MissCount())
+	assert.Equal(t, uint64(2), stats.NegativeHitCount())
+	assert.Equal(t, uint64(3), stats.RequestCount())
+	assert.Equal(t, uint64(1), stats.LoadFailureCount())
+}
+
+func TestGetAllCachesTheKeysABulkLoaderDidNotFind(t *testing.T) {
+	var loaded [][]int
+	cache := goffeine.NewBuilder[int, string]().MaximumSize(100).CacheNotFound(time.Minute).
+		BuildLoading(evenBulkLoader{&loaded})
+
+	entries, err := cache.GetAll([]int{1, 2, 3, 4})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]string{2: "2", 4: "4"}, entries)
+	entries, err = cache.GetAll([]int{1, 2, 3, 4, 5})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]string{2: "2", 4: "4"}, entries)
+	assert.Equal(t, [][]int{{1, 2, 3, 4}, {5}}, loaded)
+
+	_, err = cache.Get(3)
+	assert.ErrorIs(t, err, goffeine.ErrNotFound)
+}
+
+func TestGetAllCachesEveryKeyOfABulkLoad(t *testing.T) {
+	var loaded [][]int
+	cache := goffeine.NewBuilder[int, string]().MaximumSize(10_000).CacheNotFound(time.Minute).
+		BuildLoading(evenBulkLoader{&loaded})
+	// more keys than key lock stripes, so that some of them share one
+	keys := make([]int, 5000)
+	for i := range keys {
+		keys[i] = i
+	}
+
+	cache.GetAll(keys)
+	entries, err := cache.GetAll(keys)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2500)
+	assert.Len(t, loaded, 1)
+}
+
+// evenBulkLoader only finds the even keys, and records the keys of each LoadAll.
+type evenBulkLoader struct {
+	loaded *[][]int
+}
+
+func (l evenBulkLoader) Load(key int) (string, error) {
+	if key%2 != 0 {
+		return "", goffeine.ErrNotFound
+	}
+	return fmt.Sprint(key), nil
+}
+
+func (l evenBulkLoader) LoadAll(keys []int) (map[int]string, error) {
+	*l.loaded = append(*l.loaded, keys)
+	entries := make(map[int]string)
+	for _, key := range keys {
+		if key%2 == 0 {
+			entries[key] = fmt.Sprint(key)
+		}
+	}
+	return entries, nil
+}
+
+// racedBulkLoader puts a value for each key it is asked for, as a concurrent writer would, and then
+// fails.
+type racedBulkLoader struct {
+	cache **goffeine.LoadingCache[int, string]
+}
+
+func (l racedBulkLoader) Load(key int) (string, error) {
+	(*l.cache).Put(key, "fresh")
+	return "", errUnavailable
+}
+
+func (l racedBulkLoader) LoadAll(keys []int) (map[int]string, error) {
+	for _, key := range keys {
+		(*l.cache).Put(key, "fresh")
+	}
+	return nil, errUnavailable
+}
+
+func TestFailureDoesNotReplaceAValuePutDuringTheLoad(t *testing.T) {
+	var removals []goffeine.RemovalCause
+	var cache *goffeine.LoadingCache[int, string]
+	cache = goffeine.NewBuilder[int, string]().MaximumSize(100).CacheLoadFailures(time.Minute).
+		RemovalListener(func(key int, value string, cause goffeine.RemovalCause) {
+			removals = append(removals, cause)
+		}).BuildLoading(racedBulkLoader{&cache})
+
+	_, err := cache.Get(1)
+	assert.ErrorIs(t, err, errUnavailable)
+	_, err = cache.GetAll([]int{2, 3})
+	assert.ErrorIs(t, err, errUnavailable)
+
+	entries, err := cache.GetAll([]int{1, 2, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]string{1: "fresh", 2: "fresh", 3: "fresh"}, entries)
+	assert.Empty(t, removals)
+}
@@ -0,0 +1,143 @@
+package goffeine
+
+import (
+	"container/list"
+)
+
+// A Policy inspects the eviction policy of a cache at runtime. It is obtained with Goffeine.Policy.
+type Policy[K comparable, V any] struct {
+	g *Goffeine[K, V]
+}
+
+// An Entry is a snapshot of a cache entry.
+type Entry[K comparable, V any] struct {
+	Key    K
+	Value  V
+	Weight int
+}
+
+// A Region is a snapshot of the occupancy of one of the policy's regions. The weights and maximum
+// are entry counts unless the cache is bounded by MaximumWeight.
+type Region struct {
+	Size         int
+	WeightedSize int
+	MaximumSize  int
+}
+
+// Policy returns a view of the cache's eviction policy.
+func (g *Goffeine[K, V]) Policy() Policy[K, V] {
+	return Policy[K, V]{g: g}
+}
+
+// IsWeighted reports whether the cache is bounded by MaximumWeight.
+func (p Policy[K, V]) IsWeighted() bool { return p.g.MaximumWeight() > 0 }
+
+// Maximum returns the maximum weighted size of the cache.
+func (p Policy[K, V]) Maximum() (maximum int) {
+	p.g.locked(func() { maximum = p.g.maximum() })
+	return maximum
+}
+
+// SetMaximum resizes the cache to maximum, in weight when it is bounded by MaximumWeight. The
+// regions are resized the way Builder.Build sizes them, the frequency sketch grows when needed and
+// the entries over the new bound are evicted right away.
+func (p Policy[K, V]) SetMaximum(maximum int) {
+	p.g.locked(func() { p.g.setMaximum(maximum) })
+}
+
+// WeightedSize returns the total weight of the entries in the cache.
+func (p Policy[K, V]) WeightedSize() int { return p.g.WeightedSize() }
+
+// Window returns the occupancy of the admission window.
+func (p Policy[K, V]) Window() (region Region) {
+	p.g.locked(func() { region = Region{p.g.window.Len(), p.g.windowWeight, p.g.windowMaximumSize} })
+	return region
+}
+
+// Probation returns the occupancy of the probation region.
+func (p Policy[K, V]) Probation() (region Region) {
+	p.g.locked(func() { region = Region{p.g.probation.Len(), p.g.probationWeight, p.g.probationMaximumSize} })
+	return region
+}
+
+// Protected returns the occupancy of the protected region.
+func (p Policy[K, V]) Protected() (region Region) {
+	p.g.locked(func() { region = Region{p.g.protected.Len(), p.g.protectedWeight, p.g.protectedMaximumSize} })
+	return region
+}
+
+// Coldest returns at most n entries, ordered from the one most likely to be evicted next. The
+// least recently used entries of the window and probation come first, the more frequent of the
+// two last, followed by protected from its least recently used entry. Neither the recency nor the
+// frequency of the entries is changed, and the expired ones are skipped.
+func (p Policy[K, V]) Coldest(n int) (entries []Entry[K, V]) {
+	p.g.locked(func() {
+		primary := p.merge(p.g.window.Back(), p.g.probation.Back(), (*list.Element).Prev, func(a, b int) bool { return a <= b })
+		entries = p.snapshot(n, primary, walk(p.g.protected.Back(), (*list.Element).Prev))
+	})
+	return entries
+}
+
+// Hottest returns at most n entries, ordered from the one least likely to be evicted. Protected
+// comes first from its most recently used entry, followed by the most recently used entries of
+// probation and the window, the more frequent of the two first. Neither the recency nor the
+// frequency of the entries is changed, and the expired ones are skipped.
+func (p Policy[K, V]) Hottest(n int) (entries []Entry[K, V]) {
+	p.g.locked(func() {
+		secondary := p.merge(p.g.probation.Front(), p.g.window.Front(), (*list.Element).Next, func(a, b int) bool { return a >= b })
+		entries = p.snapshot(n, walk(p.g.protected.Front(), (*list.Element).Next), secondary)
+	})
+	return entries
+}
+
+// walk returns an iterator over the elements from ele, following next.
+func walk(ele *list.Element, next func(*list.Element) *list.Element) func() *list.Element {
+	return func() *list.Element {
+		current := ele
+		if ele != nil {
+			ele = next(ele)
+		}
+		return current
+	}
+}
+
+// merge returns an iterator over the elements from a and b, following next, that picks from a
+// while before holds for the frequencies of the two heads.
+func (p Policy[K, V]) merge(a, b *list.Element, next func(*list.Element) *list.Element, before func(a, b int) bool) func() *list.Element {
+	frequency := func(ele *list.Element) int {
+		return p.g.fsketch.Frequency(nodeOf[K, V](ele).Key)
+	}
+	return func() *list.Element {
+		var current *list.Element
+		switch {
+		case a == nil && b == nil:
+			return nil
+		case b == nil || (a != nil && before(frequency(a), frequency(b))):
+			current, a = a, next(a)
+		default:
+			current, b = b, next(b)
+		}
+		return current
+	}
+}
+
+// snapshot collects at most n live entries from the iterators, one after the other. The caller
+// holds evictionLock.
+func (p Policy[K, V]) snapshot(n int, iterators ...func() *list.Element) []Entry[K, V] {
+	if n <= 0 {
+		return nil
+	}
+	now := p.g.ticker.Read()
+	entries := make([]Entry[K, V], 0, min(n, p.g.window.Len()+p.g.probation.Len()+p.g.protected.Len()))
+	for _, iterator := range iterators {
+		for ele := iterator(); ele != nil && len(entries) < n; ele = iterator() {
+			gnode := nodeOf[K, V](ele)
+			value, err := gnode.Load()
+			if err != nil || gnode.IsExpired(now) {
+				continue
+			}
+			entries = append(entries, Entry[K, V]{Key: gnode.Key, Value: value, Weight: gnode.Weight})
+		}
+	}
+	return entries
+}
@@ -0,0 +1,134 @@
+package goffeine_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func keysOf(entries []goffeine.Entry[string, any]) []string {
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func newPolicyCache() *goffeine.Goffeine[string, any] {
+	cache := NewCacheWithMaximumSize(100) // window: 1, probation: 19, protected: 80
+	for i := 0; i < 10; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+	cache.Get("5") // 5 -> protected
+	cache.Get("9") // 9 stays in the window, more frequent than probation
+	return cache
+}
+
+func TestPolicyColdest(t *testing.T) {
+	cache := newPolicyCache()
+	policy := cache.Policy()
+
+	assert.Equal(t, []string{"0", "1", "2"}, keysOf(policy.Coldest(3)))
+	assert.Equal(t, []string{"0", "1", "2", "3", "4", "6", "7", "8", "9", "5"}, keysOf(policy.Coldest(100)))
+	assert.Empty(t, policy.Coldest(0))
+
+	// inspecting must not promote anything
+	assert.Equal(t, []string{"0", "1", "2"}, keysOf(policy.Coldest(3)))
+}
+
+func TestPolicyHottest(t *testing.T) {
+	cache := newPolicyCache()
+	policy := cache.Policy()
+
+	assert.Equal(t, []string{"5", "9", "8"}, keysOf(policy.Hottest(3)))
+	assert.Equal(t, []string{"5", "9", "8", "7", "6", "4", "3", "2", "1", "0"}, keysOf(policy.Hottest(100)))
+	entry := policy.Hottest(1)[0]
+	assert.Equal(t, 5, entry.Value)
+	assert.Equal(t, 1, entry.Weight)
+}
+
+func TestPolicyRegions(t *testing.T) {
+	cache := newPolicyCache()
+	policy := cache.Policy()
+
+	assert.False(t, policy.IsWeighted())
+	assert.Equal(t, 100, policy.Maximum())
+	assert.Equal(t, 10, policy.WeightedSize())
+	assert.Equal(t, goffeine.Region{Size: 1, WeightedSize: 1, MaximumSize: 1}, policy.Window())
+	assert.Equal(t, goffeine.Region{Size: 8, WeightedSize: 8, MaximumSize: 19}, policy.Probation())
+	assert.Equal(t, goffeine.Region{Size: 1, WeightedSize: 1, MaximumSize: 80}, policy.Protected())
+}
+
+func TestPolicyWeightedRegions(t *testing.T) {
+	cache := newBlobCache(1000)
+	cache.Put("a", "0123456789")
+	cache.Put("b", "01234")
+	policy := cache.Policy()
+
+	assert.True(t, policy.IsWeighted())
+	assert.Equal(t, 1000, policy.Maximum())
+	assert.Equal(t, 15, policy.WeightedSize())
+	assert.Equal(t, goffeine.Region{Size: 1, WeightedSize: 5, MaximumSize: 10}, policy.Window())
+	assert.Equal(t, goffeine.Region{Size: 1, WeightedSize: 10, MaximumSize: 198}, policy.Probation())
+	// on a tie in frequency the window comes first
+	assert.Equal(t, []goffeine.Entry[string, string]{{Key: "b", Value: "01234", Weight: 5}}, policy.Coldest(1))
+}
+
+func TestPolicySkipsExpiredEntries(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).Ticker(ticker).Build()
+	cache.PutWithDelay("a", 1, 1000)
+	cache.Put("b", 2)
+	ticker.Advance(2 * time.Second)
+
+	assert.Equal(t, 2, cache.EstimatedSize())
+	assert.Equal(t, []goffeine.Entry[string, int]{{Key: "b", Value: 2, Weight: 1}}, cache.Policy().Hottest(10))
+}
+
+func TestPolicySetMaximumShrinks(t *testing.T) {
+	var causes []goffeine.RemovalCause
+	cache := goffeine.NewBuilder[int, int]().MaximumSize(1000).RecordStats().
+		RemovalListener(func(key int, value int, cause goffeine.RemovalCause) { causes = append(causes, cause) }).Build()
+	for i := 0; i < 1000; i++ {
+		cache.Put(i, i)
+	}
+	cache.Policy().SetMaximum(100)
+
+	assert.Equal(t, 100, cache.MaximumSize())
+	assert.Equal(t, 100, cache.EstimatedSize())
+	assert.Equal(t, 1, cache.WindowMaximumSize())
+	assert.Equal(t, 19, cache.ProbationMaximumSize())
+	assert.Equal(t, 80, cache.ProtectedMaximumSize())
+	assert.LessOrEqual(t, cache.WindowSize(), 1)
+	assert.LessOrEqual(t, cache.ProtectedSize(), 80)
+	assert.Equal(t, uint64(900), cache.Stats().EvictionCount())
+	assert.Len(t, causes, 900)
+	for _, cause := range causes {
+		assert.Equal(t, goffeine.Size, cause)
+	}
+}
+
+func TestPolicySetMaximumGrows(t *testing.T) {
+	cache := goffeine.NewBuilder[int, int]().MaximumSize(10).Build()
+	cache.Policy().SetMaximum(1000)
+	for i := 0; i < 1000; i++ {
+		cache.Put(i, i)
+	}
+	assert.Equal(t, 1000, cache.Policy().Maximum())
+	assert.Equal(t, 1000, cache.EstimatedSize())
+}
+
+func TestPolicySetMaximumWeight(t *testing.T) {
+	cache := newBlobCache(1000)
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), "0123456789")
+	}
+	cache.Policy().SetMaximum(500)
+
+	assert.Equal(t, 500, cache.MaximumWeight())
+	assert.Equal(t, 0, cache.MaximumSize())
+	assert.LessOrEqual(t, cache.WeightedSize(), 500)
+	assert.Equal(t, 50, cache.EstimatedSize())
+}
@@ -0,0 +1,89 @@
+package goffeine
+
+import (
+	"goffeine/internal/node"
+	"time"
+)
+
+// A refreshResult is the outcome of a background reload, waiting to be applied by maintenance.
+type refreshResult[K comparable, V any] struct {
+	gnode     *node.GoffeineNode[K, V]
+	writeTime int64
+	value     V
+	err       error
+}
+
+// refreshes reports whether entries are reloaded once they are older than RefreshAfterWrite.
+func (g *Goffeine[K, V]) refreshes() bool {
+	return g.refreshMilliseconds > 0 && g.loader != nil
+}
+
+// refreshIfNeeded starts a background reload of the node if it was written at least
+// refreshMilliseconds before now and no reload is in flight yet. The caller keeps the current value.
+func (g *Goffeine[K, V]) refreshIfNeeded(gnode *node.GoffeineNode[K, V], now int64) {
+	if !g.refreshes() || gnode.Refreshing.Load() {
+		return
+	}
+	if now-gnode.WriteTime.Load() < g.refreshMilliseconds*int64(time.Millisecond) {
+		return
+	}
+	g.reload(gnode)
+}
+
+// reload starts a background reload of the node with the loader, unless there is no loader or a
+// reload is in flight already. The caller keeps the current value.
+func (g *Goffeine[K, V]) reload(gnode *node.GoffeineNode[K, V]) {
+	if g.loader == nil || !gnode.Refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	writeTime := gnode.WriteTime.Load()
+	key := gnode.Key
+	go func() {
+		start := g.ticker.Read()
+		value, err := g.loader.Load(key)
+		g.stats.recordLoad(err, time.Duration(g.ticker.Read()-start))
+		g.refreshLock.Lock()
+		g.refreshed = append(g.refreshed, refreshResult[K, V]{gnode, writeTime, value, err})
+		g.pendingRefreshes.Add(1)
+		g.refreshLock.Unlock()
+	}()
+}
+
+// applyRefreshes replaces the values of the entries whose reload completed. A reloaded value is
+// dropped if its entry was written or removed while the reload was in flight, or if it failed.
+// The caller holds evictionLock.
+func (g *Goffeine[K, V]) applyRefreshes(now int64) {
+	if g.pendingRefreshes.Load() == 0 {
+		return
+	}
+	g.refreshLock.Lock()
+	results := g.refreshed
+	g.refreshed = nil
+	g.pendingRefreshes.Add(-int32(len(results)))
+	g.refreshLock.Unlock()
+
+	for _, r := range results {
+		gnode := r.gnode
+		gnode.Refreshing.Store(false)
+		if r.err != nil {
+			continue
+		}
+		gnode.Lock()
+		if !gnode.IsAlive() || gnode.WriteTime.Load() != r.writeTime {
+			gnode.Unlock()
+			continue
+		}
+		oldValue := gnode.Value()
+		gnode.SetValue(r.value)
+		gnode.WriteWeight.Store(int64(g.weigh(gnode.Key, r.value)))
+		gnode.WriteTime.Store(now)
+		g.expireAfterWrite(gnode, now, false)
+		gnode.Unlock()
+
+		if gnode.AccessElement != nil {
+			g.setWeight(gnode, int(gnode.WriteWeight.Load()))
+			g.reschedule(gnode)
+		}
+		g.removals = append(g.removals, removal[K, V]{gnode.Key, oldValue, Replaced})
+	}
+}
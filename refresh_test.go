@@ -0,0 +1,106 @@
+package goffeine_test
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForValue runs maintenance until the cache holds the expected value for key
+func waitForValue[K comparable, V any](t *testing.T, cache *goffeine.Goffeine[K, V], key K, expected V) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		cache.CleanUp()
+		if v, ok := cache.Get(key); ok && any(v) == any(expected) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%v was never refreshed to %v", key, expected)
+}
+
+// waitForReloads waits until n reloads of cache have completed, for maintenance to apply them.
+func waitForReloads[K comparable, V any](t *testing.T, cache *goffeine.Goffeine[K, V], n int) {
+	deadline := time.Now().Add(time.Second)
+	for cache.PendingRefreshes() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d reloads never completed", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRefreshAfterWrite(t *testing.T) {
+	ticker := &FakeTicker{}
+	var loads atomic.Int32
+	loader := goffeine.LoaderFunc[string, int32](func(key string) (int32, error) {
+		return loads.Add(1) * 10, nil
+	})
+	cache := goffeine.NewBuilder[string, int32]().MaximumSize(100).RefreshAfterWrite(time.Minute, 1).
+		CacheLoader(loader).Ticker(ticker).Build()
+	cache.Put("a", 1)
+
+	ticker.Advance(30 * time.Second)
+	v, _ := cache.Get("a")
+	assert.Equal(t, int32(1), v)
+	assert.Equal(t, int32(0), loads.Load())
+
+	// the stale value is served while the reload runs in the background
+	ticker.Advance(30 * time.Second)
+	v, _ = cache.Get("a")
+	assert.Equal(t, int32(1), v)
+	waitForValue(t, cache, "a", int32(10))
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestRefreshIsDroppedWhenTheEntryChanged(t *testing.T) {
+	ticker := &FakeTicker{}
+	release := make(chan struct{})
+	var loads atomic.Int32
+	loader := goffeine.LoaderFunc[string, int](func(key string) (int, error) {
+		<-release
+		loads.Add(1)
+		return 100, nil
+	})
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).RefreshAfterWrite(time.Minute, 1).
+		CacheLoader(loader).Ticker(ticker).Build()
+	cache.Put("a", 1)
+	ticker.Advance(time.Minute)
+	cache.Get("a") // starts the reload
+
+	cache.Put("a", 2)
+	close(release)
+	waitForReloads(t, cache, 1)
+	cache.CleanUp()
+
+	assert.Equal(t, 0, cache.PendingRefreshes())
+	assert.Equal(t, int32(1), loads.Load())
+	v, _ := cache.Get("a")
+	assert.Equal(t, 2, v)
+}
+
+func TestRefreshFailureKeepsTheValue(t *testing.T) {
+	ticker := &FakeTicker{}
+	var loads atomic.Int32
+	loader := goffeine.LoaderFunc[string, int](func(key string) (int, error) {
+		if loads.Add(1) == 1 {
+			return 0, errors.New("backend is down")
+		}
+		return 2, nil
+	})
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).RefreshAfterWrite(time.Minute, 1).
+		CacheLoader(loader).Ticker(ticker).Build()
+	cache.Put("a", 1)
+	ticker.Advance(time.Minute)
+	cache.Get("a")
+	waitForReloads(t, cache, 1)
+	cache.CleanUp()
+
+	v, ok := cache.Get("a") // the failed reload is retried
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	waitForValue(t, cache, "a", 2)
+}
@@ -0,0 +1,67 @@
+package goffeine
+
+// A RemovalCause tells why an entry left the cache.
+type RemovalCause int
+
+const (
+	// Explicit means the entry was removed by Invalidate, InvalidateAll or InvalidateIf.
+	Explicit RemovalCause = iota
+	// Replaced means the value was replaced by a write or a refresh; the key stays in the cache.
+	Replaced
+	// Collected means the entry was reclaimed by the garbage collector. It is kept for parity with
+	// Caffeine's weak and soft values; the cache holds strong references so it is never reported.
+	Collected
+	// Expired means the entry's expiration deadline passed.
+	Expired
+	// Size means the entry was evicted to keep the cache within its maximum size.
+	Size
+)
+
+func (c RemovalCause) String() string {
+	switch c {
+	case Explicit:
+		return "Explicit"
+	case Replaced:
+		return "Replaced"
+	case Collected:
+		return "Collected"
+	case Expired:
+		return "Expired"
+	case Size:
+		return "Size"
+	default:
+		return "Unknown"
+	}
+}
+
+// WasEvicted reports whether the entry was removed automatically by the cache, rather than by the
+// user.
+func (c RemovalCause) WasEvicted() bool {
+	return c == Collected || c == Expired || c == Size
+}
+
+// A RemovalListener is notified of every value that leaves the cache. It is called synchronously
+// by the goroutine that removed the entry, so it should be quick.
+type RemovalListener[K comparable, V any] func(key K, value V, cause RemovalCause)
+
+// A removal is the notification of a removal made under evictionLock, held back until the lock is
+// released.
+type removal[K comparable, V any] struct {
+	key   K
+	value V
+	cause RemovalCause
+}
+
+// deliver notifies the removal listener of r, if there was a removal.
+func (g *Goffeine[K, V]) deliver(r *removal[K, V]) {
+	if r != nil {
+		g.notifyRemoval(r.key, r.value, r.cause)
+	}
+}
+
+// notifyRemoval calls the removal listener, if there is one.
+func (g *Goffeine[K, V]) notifyRemoval(key K, value V, cause RemovalCause) {
+	if g.removalListener != nil {
+		g.removalListener(key, value, cause)
+	}
+}
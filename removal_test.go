@@ -0,0 +1,68 @@
+package goffeine_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type Removal struct {
+	Key   string
+	Value int
+	Cause goffeine.RemovalCause
+}
+
+func newListeningCache(maxSize int, ticker goffeine.Ticker, removals *[]Removal) *goffeine.Goffeine[string, int] {
+	return goffeine.NewBuilder[string, int]().MaximumSize(maxSize).ExpireAfterWrite(time.Second, 10).Ticker(ticker).
+		RemovalListener(func(key string, value int, cause goffeine.RemovalCause) {
+			*removals = append(*removals, Removal{key, value, cause})
+		}).Build()
+}
+
+func TestRemovalListenerExplicitAndReplaced(t *testing.T) {
+	var removals []Removal
+	cache := newListeningCache(100, &FakeTicker{}, &removals)
+	cache.Put("a", 1)
+	cache.Put("a", 2)
+	cache.Put("b", 3)
+	cache.Put("c", 4)
+	cache.Invalidate("a")
+	cache.InvalidateIf(func(key string, value int) bool { return value == 3 })
+	cache.InvalidateAll()
+
+	assert.Equal(t, []Removal{
+		{"a", 1, goffeine.Replaced},
+		{"a", 2, goffeine.Explicit},
+		{"b", 3, goffeine.Explicit},
+		{"c", 4, goffeine.Explicit},
+	}, removals)
+}
+
+func TestRemovalListenerExpired(t *testing.T) {
+	var removals []Removal
+	ticker := &FakeTicker{}
+	cache := newListeningCache(100, ticker, &removals)
+	cache.Put("a", 1)
+	ticker.Advance(time.Minute)
+	cache.CleanUp()
+
+	assert.Equal(t, []Removal{{"a", 1, goffeine.Expired}}, removals)
+	assert.True(t, removals[0].Cause.WasEvicted())
+}
+
+func TestRemovalListenerSize(t *testing.T) {
+	var removals []Removal
+	cache := newListeningCache(100, &FakeTicker{}, &removals)
+	for i := 0; i < 150; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+
+	assert.Equal(t, 50, len(removals))
+	for _, removal := range removals {
+		assert.Equal(t, goffeine.Size, removal.Cause)
+		_, ok := cache.Get(removal.Key)
+		assert.False(t, ok)
+	}
+}
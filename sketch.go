@@ -2,6 +2,7 @@ package goffeine
 
 import (
 	"goffeine/internal/utils"
+	"hash/maphash"
 	"math"
 )
 
@@ -46,24 +47,28 @@ const (
 	Long1     int64 = 1
 )
 
-func NewSketch(maximumSize int) *FrequencySketch {
-	fs := FrequencySketch{}
+func NewSketch[K comparable](maximumSize int) *FrequencySketch[K] {
+	fs := FrequencySketch[K]{seed: maphash.MakeSeed()}
 	fs.EnsureCapacity(maximumSize)
 	return &fs
 }
 
-type FrequencySketch struct {
+type FrequencySketch[K comparable] struct {
 	SampleSize int
 	BlockMask  int
 	Table      []int64
 	Size       int
+	seed       maphash.Seed
 }
 
 // EnsureCapacity
 // Initializes and increases the capacity of this FrequencySketch instance, if necessary,
 // to ensure that it can accurately estimate the popularity of elements given the maximum Size of
 // the cache. This operation forgets all previous counts when resizing.
-func (f *FrequencySketch) EnsureCapacity(maximumSize int) {
+func (f *FrequencySketch[K]) EnsureCapacity(maximumSize int) {
+	if f.seed == (maphash.Seed{}) {
+		f.seed = maphash.MakeSeed()
+	}
 	maximum := int(utils.Min(maximumSize, int(uint(math.MaxInt32)>>1)))
 	if len(f.Table) > maximum {
 		return
@@ -88,9 +93,9 @@ func (f *FrequencySketch) EnsureCapacity(maximumSize int) {
 // Returns the estimated number of occurrences of an element, up to the maximum (15).
 // @param e the element to count occurrences of
 // @return the estimated number of occurrences of the element; possibly zero but never negative
-func (f *FrequencySketch) Frequency(e string) int {
+func (f *FrequencySketch[K]) Frequency(e K) int {
 	count := make([]int, 4)
-	blockHash := spread(hashCode(e))
+	blockHash := spread(f.hashCode(e))
 	counterHash := rehash(blockHash)
 	block := (blockHash & f.BlockMask) << 3
 
@@ -107,18 +112,8 @@ func (f *FrequencySketch) Frequency(e string) int {
 // Increments the popularity of the element if it does not exceed the maximum (15). The popularity
 // of all elements will be periodically down sampled when the observed events exceed a threshold.
 // This process provides a frequency aging to allow expired long term entries to fade away.
-func (f *FrequencySketch) Increment(e string) {
-	index := make([]int, 8)
-	blockHash := spread(hashCode(e))
-	counterHash := rehash(blockHash)
-	block := (blockHash & f.BlockMask) << 3
-	for i := 0; i < 4; i++ {
-		h := int(uint(counterHash) >> (i << 3))
-		index[i] = int(uint(h)>>1) & 15
-		offset := h & 1
-		index[i+4] = block + offset + (i << 1)
-	}
-
+func (f *FrequencySketch[K]) Increment(e K) {
+	index := f.indexOf(e)
 	added := f.incrementAt(index[4], index[0])
 	added = f.incrementAt(index[5], index[1]) || added
 	added = f.incrementAt(index[6], index[2]) || added
@@ -130,12 +125,42 @@ func (f *FrequencySketch) Increment(e string) {
 	}
 }
 
+// Restore raises the counters of the element to at least frequency, up to the maximum (15), e.g.
+// to restore a snapshot. Unlike Increment, it does not count towards the sample, so it never ages
+// the sketch.
+func (f *FrequencySketch[K]) Restore(e K, frequency int) {
+	frequency = min(frequency, 15)
+	index := f.indexOf(e)
+	for i := 0; i < 4; i++ {
+		offset := index[i] << 2
+		if int((f.Table[index[i+4]]>>offset)&LongF) < frequency {
+			f.Table[index[i+4]] = f.Table[index[i+4]]&^(LongF<<offset) | int64(frequency)<<offset
+		}
+	}
+}
+
+// indexOf returns the counters of the element: the counter of each of the 4 depths, followed by
+// the Table index that holds it.
+func (f *FrequencySketch[K]) indexOf(e K) []int {
+	index := make([]int, 8)
+	blockHash := spread(f.hashCode(e))
+	counterHash := rehash(blockHash)
+	block := (blockHash & f.BlockMask) << 3
+	for i := 0; i < 4; i++ {
+		h := int(uint(counterHash) >> (i << 3))
+		index[i] = int(uint(h)>>1) & 15
+		offset := h & 1
+		index[i+4] = block + offset + (i << 1)
+	}
+	return index
+}
+
 // Increments the specified counter by 1 if it is not already at the maximum value (15).
 //
 // @param i the Table index (16 counters)
 // @param j the counter to increment
 // @return if incremented
-func (f *FrequencySketch) incrementAt(i int, j int) bool {
+func (f *FrequencySketch[K]) incrementAt(i int, j int) bool {
 	offset := j << 2
 	mask := LongF << offset
 	if (f.Table[i] & mask) != mask {
@@ -146,7 +171,7 @@ func (f *FrequencySketch) incrementAt(i int, j int) bool {
 }
 
 // Reset Reduces every counter by half of its original value.
-func (f *FrequencySketch) Reset() {
+func (f *FrequencySketch[K]) Reset() {
 	count := 0
 	for i := 0; i < len(f.Table); i++ {
 		count += BitCount64(f.Table[i] & OneMask)
@@ -156,13 +181,9 @@ func (f *FrequencySketch) Reset() {
 	f.Size = int(uint(f.Size) >> 1)
 }
 
-// hashCode is a simple hash function that returns an int.
-func hashCode(s string) int {
-	h := 0
-	for _, v := range []byte(s) {
-		h = 31*h + int(v&0xff)
-	}
-	return h
+// hashCode hashes any comparable key with the sketch's seed, so keys do not need to be strings.
+func (f *FrequencySketch[K]) hashCode(e K) int {
+	return int(maphash.Comparable(f.seed, e))
 }
 
 // spread Applies a supplemental hash function to defend against a poor quality hash.
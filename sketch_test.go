@@ -10,7 +10,7 @@ import (
 )
 
 func TestEnsureCapacity_smaller(t *testing.T) {
-	sketch := goffeine.NewSketch(512)
+	sketch := goffeine.NewSketch[string](512)
 	size := len(sketch.Table)
 	sketch.EnsureCapacity(size / 2)
 	assert.Equal(t, size, len(sketch.Table))
@@ -19,7 +19,7 @@ func TestEnsureCapacity_smaller(t *testing.T) {
 }
 
 func TestEnsureCapacity_larger(t *testing.T) {
-	sketch := goffeine.NewSketch(512)
+	sketch := goffeine.NewSketch[string](512)
 	size := len(sketch.Table)
 	sketch.EnsureCapacity(2 * size)
 	assert.Equal(t, 2*size, len(sketch.Table))
@@ -28,9 +28,9 @@ func TestEnsureCapacity_larger(t *testing.T) {
 }
 
 func TestEnsureCapacity_maximum(t *testing.T) {
-	sketch := goffeine.NewSketch(512)
+	sketch := goffeine.NewSketch[string](512)
 	size := math.MaxInt32/10 + 1
-	sketch = goffeine.NewSketch(size)
+	sketch = goffeine.NewSketch[string](size)
 
 	assert.Equal(t, math.MaxInt32, sketch.SampleSize)
 	assert.Equal(t, utils.CeilingPowerOfTwo32(size), len(sketch.Table))
@@ -38,14 +38,14 @@ func TestEnsureCapacity_maximum(t *testing.T) {
 }
 
 func TestIncrement_once(t *testing.T) {
-	sketch := goffeine.NewSketch(512)
+	sketch := goffeine.NewSketch[string](512)
 	item := "key1"
 	sketch.Increment(item)
 	assert.Equal(t, 1, sketch.Frequency(item))
 }
 
 func TestIncrement_max(t *testing.T) {
-	sketch := goffeine.NewSketch(512)
+	sketch := goffeine.NewSketch[string](512)
 	item := "key1"
 	for i := 0; i < 20; i++ {
 		sketch.Increment(item)
@@ -53,8 +53,29 @@ func TestIncrement_max(t *testing.T) {
 	assert.Equal(t, 15, sketch.Frequency(item))
 }
 
+func TestRestore(t *testing.T) {
+	sketch := goffeine.NewSketch[string](512)
+	for i := 0; i < 5; i++ {
+		sketch.Increment("key1")
+	}
+	sketch.Restore("key1", 3)
+	sketch.Restore("key2", 20)
+
+	assert.Equal(t, 5, sketch.Frequency("key1"), "a counter is never lowered")
+	assert.Equal(t, 15, sketch.Frequency("key2"))
+}
+
+func TestRestore_doesNotReset(t *testing.T) {
+	sketch := goffeine.NewSketch[string](8)
+	for i := 0; i < sketch.SampleSize; i++ {
+		sketch.Restore(strconv.Itoa(i), 15)
+	}
+	assert.Equal(t, 0, sketch.Size)
+	assert.Equal(t, 15, sketch.Frequency("0"))
+}
+
 func TestIncrement_distinct(t *testing.T) {
-	sketch := goffeine.NewSketch(512)
+	sketch := goffeine.NewSketch[string](512)
 	sketch.Increment("key1")
 	sketch.Increment("key1_1")
 	assert.Equal(t, 1, sketch.Frequency("key1"))
@@ -63,14 +84,14 @@ func TestIncrement_distinct(t *testing.T) {
 }
 
 func TestIncrement_zero(t *testing.T) {
-	sketch := goffeine.NewSketch(512)
+	sketch := goffeine.NewSketch[string](512)
 	sketch.Increment("")
 	assert.Equal(t, 1, sketch.Frequency(""))
 }
 
 func TestReset(t *testing.T) {
 	reset := false
-	sketch := goffeine.NewSketch(64)
+	sketch := goffeine.NewSketch[string](64)
 	sketch.EnsureCapacity(64)
 
 	for i := 1; i < 20*len(sketch.Table); i++ {
@@ -84,8 +105,20 @@ func TestReset(t *testing.T) {
 	assert.Greater(t, sketch.SampleSize/2, sketch.Size)
 }
 
+func TestIncrement_structKey(t *testing.T) {
+	type key struct {
+		tenant string
+		id     int
+	}
+	sketch := goffeine.NewSketch[key](512)
+	sketch.Increment(key{"a", 1})
+	sketch.Increment(key{"a", 1})
+	assert.Equal(t, 2, sketch.Frequency(key{"a", 1}))
+	assert.Equal(t, 0, sketch.Frequency(key{"a", 2}))
+}
+
 func TestFull(t *testing.T) {
-	sketch := goffeine.NewSketch(512)
+	sketch := goffeine.NewSketch[string](512)
 	sketch.SampleSize = math.MaxInt32
 
 	for i := 0; i < 100_000; i++ {
@@ -95,8 +128,8 @@ func TestFull(t *testing.T) {
 	//	assert.Equal(t, 64, goffeine.BitCount64(int64(item)))
 	//}
 	sketch.Reset()
-	for item := range sketch.Table {
-		assert.Equal(t, goffeine.ResetMask, int64(item))
+	for _, item := range sketch.Table {
+		assert.Equal(t, goffeine.ResetMask, item)
 	}
 }
 
@@ -0,0 +1,235 @@
+package goffeine
+
+import (
+	"container/list"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"goffeine/internal/node"
+	"io"
+	"time"
+)
+
+// snapshotVersion is the version of the format written by SaveTo. Version 2 added SavedAt.
+const snapshotVersion = 2
+
+// ErrSnapshotVersion is returned by LoadFrom for a snapshot it does not know how to read.
+var ErrSnapshotVersion = errors.New("goffeine: unsupported snapshot version")
+
+// A Codec encodes the keys and values of a cache for a snapshot, and decodes them back.
+type Codec[K comparable, V any] interface {
+	EncodeKey(key K) ([]byte, error)
+	DecodeKey(data []byte) (K, error)
+	EncodeValue(value V) ([]byte, error)
+	DecodeValue(data []byte) (V, error)
+}
+
+// A JSONCodec encodes keys and values with encoding/json.
+type JSONCodec[K comparable, V any] struct{}
+
+func (JSONCodec[K, V]) EncodeKey(key K) ([]byte, error) { return json.Marshal(key) }
+
+func (JSONCodec[K, V]) DecodeKey(data []byte) (key K, err error) {
+	err = json.Unmarshal(data, &key)
+	return key, err
+}
+
+func (JSONCodec[K, V]) EncodeValue(value V) ([]byte, error) { return json.Marshal(value) }
+
+func (JSONCodec[K, V]) DecodeValue(data []byte) (value V, err error) {
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// A snapshotHeader starts a snapshot, and is followed by Count entries. SavedAt is the wall clock
+// time of the snapshot in Unix nanoseconds, as the tickers of two processes are not comparable.
+type snapshotHeader struct {
+	Version int
+	Count   int
+	SavedAt int64
+}
+
+// A snapshotEntry is an entry of a snapshot. The times are in nanoseconds relative to when the
+// snapshot was taken, and the deadlines are zero when the entry does not have them.
+type snapshotEntry struct {
+	Key, Value []byte
+	Position   node.Position
+	// Frequency is the sketch's estimate for the key. The sketch itself is never saved, as its
+	// counters are indexed with the hash seed of the process that filled it: the popularity of the
+	// keys that were not cached is lost.
+	Frequency      int
+	Age            int64
+	ExpiresIn      int64
+	WriteExpiresIn int64
+}
+
+// A savedNode is an entry copied under evictionLock by SaveTo, to be encoded once it is released.
+type savedNode[K comparable, V any] struct {
+	key   K
+	value V
+	entry snapshotEntry
+}
+
+// SaveTo writes a snapshot of the cache to w, for LoadFrom to warm up a cache with, e.g. after a
+// restart. The snapshot holds the live entries with their region, their recency order, their
+// remaining time to live and how popular they were. Expired entries and cached failures are left
+// out. The entries are copied under the eviction lock, but encoded and written after it is
+// released.
+func (g *Goffeine[K, V]) SaveTo(w io.Writer, codec Codec[K, V]) error {
+	var saved []savedNode[K, V]
+	savedAt := time.Now().UnixNano()
+	g.locked(func() {
+		now := g.ticker.Read()
+		saved = make([]savedNode[K, V], 0, g.window.Len()+g.probation.Len()+g.protected.Len())
+		// from the coldest to the hottest entry of each region, the order LoadFrom links them in
+		for _, region := range []*list.List{&g.window, &g.probation, &g.protected} {
+			for ele := region.Back(); ele != nil; ele = ele.Prev() {
+				gnode := nodeOf[K, V](ele)
+				value, err := gnode.Load()
+				if err != nil || gnode.IsExpired(now) {
+					continue
+				}
+				saved = append(saved, savedNode[K, V]{gnode.Key, value, snapshotEntry{
+					Position:       gnode.Position,
+					Frequency:      g.fsketch.Frequency(gnode.Key),
+					Age:            now - gnode.WriteTime.Load(),
+					ExpiresIn:      relativeTo(gnode.ExpireTime.Load(), now),
+					WriteExpiresIn: relativeTo(gnode.WriteExpireTime.Load(), now),
+				}})
+			}
+		}
+	})
+
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(snapshotHeader{snapshotVersion, len(saved), savedAt}); err != nil {
+		return err
+	}
+	for _, s := range saved {
+		var err error
+		if s.entry.Key, err = codec.EncodeKey(s.key); err != nil {
+			return err
+		}
+		if s.entry.Value, err = codec.EncodeValue(s.value); err != nil {
+			return err
+		}
+		if err = encoder.Encode(s.entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFrom reads a snapshot written by SaveTo from r into the cache. The entries get back their
+// region, recency order, remaining time to live and popularity, and are weighed again. The time
+// since SaveTo, e.g. while the process restarted, counts against the time to live, and the entries
+// that expired meanwhile are skipped. An entry whose key is in the cache already is skipped too, as
+// the live one is newer. If the cache is smaller than the snapshot, the policy evicts down to its
+// maximum as usual. On an error, the entries read until then stay in the cache.
+func (g *Goffeine[K, V]) LoadFrom(r io.Reader, codec Codec[K, V]) error {
+	decoder := gob.NewDecoder(r)
+	var header snapshotHeader
+	if err := decoder.Decode(&header); err != nil {
+		return err
+	}
+	if header.Version != snapshotVersion {
+		return ErrSnapshotVersion
+	}
+	// sized up front, as growing the sketch forgets the frequencies restored so far
+	g.locked(func() {
+		size := g.window.Len() + g.probation.Len() + g.protected.Len()
+		g.fsketch.EnsureCapacity(min(size+header.Count, max(size, g.maximum())))
+	})
+
+	downtime := max(time.Now().UnixNano()-header.SavedAt, 0)
+	defer g.scheduleDrainBuffers()
+	for i := 0; i < header.Count; i++ {
+		var entry snapshotEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return err
+		}
+		if !entry.elapse(downtime) {
+			continue
+		}
+		key, err := codec.DecodeKey(entry.Key)
+		if err != nil {
+			return err
+		}
+		value, err := codec.DecodeValue(entry.Value)
+		if err != nil {
+			return err
+		}
+		g.restore(key, value, entry)
+	}
+	return nil
+}
+
+// restore creates the entry of a snapshot, unless key is in the cache already, and buffers its
+// addition to the policy.
+func (g *Goffeine[K, V]) restore(key K, value V, entry snapshotEntry) {
+	position := entry.Position
+	if position != node.ProbationPosition && position != node.ProtectedPosition {
+		position = node.WindowPosition
+	}
+	now := g.ticker.Read()
+	gnode := node.New(key, value, position)
+	gnode.WriteWeight.Store(int64(g.weigh(key, value)))
+	gnode.WriteTime.Store(now - entry.Age)
+	gnode.ExpireTime.Store(absoluteFrom(entry.ExpiresIn, now))
+	gnode.WriteExpireTime.Store(absoluteFrom(entry.WriteExpiresIn, now))
+
+	lock := g.keyLock(key)
+	lock.Lock()
+	_, loaded := g.data.LoadOrStore(key, gnode)
+	if !loaded {
+		lock.writes++
+		g.size.Add(1)
+	}
+	lock.Unlock()
+	if !loaded {
+		g.bufferWrite(func() { g.onRestore(gnode, entry.Frequency) })
+	}
+}
+
+// onRestore links a node restored from a snapshot into its region, as the most recently used, and
+// gives its key back its frequency.
+func (g *Goffeine[K, V]) onRestore(gnode *node.GoffeineNode[K, V], frequency int) {
+	if !gnode.IsAlive() {
+		// removed before the policy got to add it
+		return
+	}
+	gnode.Weight = int(gnode.WriteWeight.Load())
+	g.linkFront(gnode, gnode.Position)
+	g.reschedule(gnode)
+	g.ensureSketchCapacity()
+	g.fsketch.Restore(gnode.Key, frequency)
+}
+
+// elapse ages the entry by d, and reports whether it is still live.
+func (e *snapshotEntry) elapse(d int64) bool {
+	e.Age += d
+	for _, expiresIn := range []*int64{&e.ExpiresIn, &e.WriteExpiresIn} {
+		if *expiresIn == 0 {
+			continue
+		}
+		if *expiresIn -= d; *expiresIn <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// relativeTo returns the time left from now until the deadline, 0 if there is no deadline.
+func relativeTo(deadline int64, now int64) int64 {
+	if deadline == 0 {
+		return 0
+	}
+	return deadline - now
+}
+
+// absoluteFrom returns the deadline that is duration after now, 0 if there is no deadline.
+func absoluteFrom(duration int64, now int64) int64 {
+	if duration == 0 {
+		return 0
+	}
+	return now + duration
+}
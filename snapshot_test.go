@@ -0,0 +1,147 @@
+package goffeine_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	build := func() *goffeine.Goffeine[int, string] {
+		return goffeine.NewBuilder[int, string]().MaximumSize(100).Build()
+	}
+	cache := build()
+	for i := 0; i < 80; i++ {
+		cache.Put(i, string(rune('a'+i%26)))
+	}
+	for i := 0; i < 20; i++ {
+		cache.Get(i)
+		cache.Get(i)
+	}
+	cache.CleanUp()
+
+	var snapshot bytes.Buffer
+	assert.Nil(t, cache.SaveTo(&snapshot, goffeine.JSONCodec[int, string]{}))
+	restored := build()
+	assert.Nil(t, restored.LoadFrom(&snapshot, goffeine.JSONCodec[int, string]{}))
+	restored.CleanUp()
+
+	assert.Equal(t, maps.Collect(cache.All()), maps.Collect(restored.All()))
+	assert.Equal(t, cache.Policy().Window(), restored.Policy().Window())
+	assert.Equal(t, cache.Policy().Probation(), restored.Policy().Probation())
+	assert.Equal(t, cache.Policy().Protected(), restored.Policy().Protected())
+	assert.Equal(t, cache.Policy().Hottest(10), restored.Policy().Hottest(10))
+}
+
+func TestSnapshotRestoresTheFrequencies(t *testing.T) {
+	cache := goffeine.NewBuilder[int, int]().MaximumSize(100).Build()
+	for i := 0; i < 50; i++ {
+		cache.Put(i, i)
+		for j := 0; j < i%10; j++ {
+			cache.Get(i)
+		}
+	}
+	cache.CleanUp()
+	var snapshot bytes.Buffer
+	assert.Nil(t, cache.SaveTo(&snapshot, goffeine.JSONCodec[int, int]{}))
+
+	restored := goffeine.NewBuilder[int, int]().MaximumSize(100).Build()
+	assert.Nil(t, restored.LoadFrom(&snapshot, goffeine.JSONCodec[int, int]{}))
+	restored.CleanUp()
+	for i := 0; i < 50; i++ {
+		assert.GreaterOrEqual(t, restored.Frequency(i), cache.Frequency(i), "key %d", i)
+		assert.GreaterOrEqual(t, restored.Frequency(i), i%10)
+	}
+}
+
+func TestSnapshotKeepsTheRemainingTimeToLive(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(10*time.Second, 1).
+		Ticker(ticker).Build()
+	cache.Put("old", 1)
+	ticker.Advance(6 * time.Second)
+	cache.Put("new", 2)
+	cache.PutWithDelay("forever", 3, 0)
+	cache.PutWithDelay("gone", 4, 1000)
+	ticker.Advance(2 * time.Second)
+
+	var snapshot bytes.Buffer
+	assert.Nil(t, cache.SaveTo(&snapshot, goffeine.JSONCodec[string, int]{}))
+
+	// the restarted process has a clock of its own
+	ticker = &FakeTicker{}
+	ticker.Advance(1000 * time.Second)
+	restored := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(10*time.Second, 1).
+		Ticker(ticker).Build()
+	assert.Nil(t, restored.LoadFrom(&snapshot, goffeine.JSONCodec[string, int]{}))
+	assert.Equal(t, map[string]int{"old": 1, "new": 2, "forever": 3}, maps.Collect(restored.All()))
+
+	ticker.Advance(3 * time.Second)
+	assert.Equal(t, map[string]int{"new": 2, "forever": 3}, maps.Collect(restored.All()))
+	ticker.Advance(5 * time.Second)
+	assert.Equal(t, map[string]int{"forever": 3}, maps.Collect(restored.All()))
+}
+
+func TestSnapshotCountsTheDowntimeAgainstTheTimeToLive(t *testing.T) {
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).Ticker(&FakeTicker{}).Build()
+	cache.PutWithDelay("short", 1, 50)
+	cache.PutWithDelay("long", 2, time.Hour.Milliseconds())
+	var snapshot bytes.Buffer
+	assert.Nil(t, cache.SaveTo(&snapshot, goffeine.JSONCodec[string, int]{}))
+
+	// the process is down for longer than the short time to live
+	time.Sleep(100 * time.Millisecond)
+	ticker := &FakeTicker{}
+	restored := goffeine.NewBuilder[string, int]().MaximumSize(100).Ticker(ticker).Build()
+	assert.Nil(t, restored.LoadFrom(&snapshot, goffeine.JSONCodec[string, int]{}))
+	assert.Equal(t, map[string]int{"long": 2}, maps.Collect(restored.All()))
+	assert.Equal(t, 1, restored.EstimatedSize())
+
+	ticker.Advance(time.Hour - 50*time.Millisecond)
+	assert.Empty(t, maps.Collect(restored.All()))
+}
+
+func TestLoadFromKeepsTheLiveEntries(t *testing.T) {
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).Build()
+	cache.PutAll(map[string]int{"a": 1, "b": 2})
+	var snapshot bytes.Buffer
+	assert.Nil(t, cache.SaveTo(&snapshot, goffeine.JSONCodec[string, int]{}))
+
+	restored := goffeine.NewBuilder[string, int]().MaximumSize(100).Build()
+	restored.Put("a", 10)
+	assert.Nil(t, restored.LoadFrom(&snapshot, goffeine.JSONCodec[string, int]{}))
+	assert.Equal(t, map[string]int{"a": 10, "b": 2}, maps.Collect(restored.All()))
+	assert.Equal(t, 2, restored.EstimatedSize())
+}
+
+func TestLoadFromEvictsDownToTheMaximum(t *testing.T) {
+	cache := goffeine.NewBuilder[int, int]().MaximumSize(200).Build()
+	for i := 0; i < 200; i++ {
+		cache.Put(i, i)
+	}
+	var snapshot bytes.Buffer
+	assert.Nil(t, cache.SaveTo(&snapshot, goffeine.JSONCodec[int, int]{}))
+
+	restored := goffeine.NewBuilder[int, int]().MaximumSize(50).Build()
+	assert.Nil(t, restored.LoadFrom(&snapshot, goffeine.JSONCodec[int, int]{}))
+	assert.Equal(t, 50, restored.WeightedSize())
+	assert.Equal(t, 50, restored.EstimatedSize())
+}
+
+func TestLoadFromRejectsAnUnknownVersion(t *testing.T) {
+	var snapshot bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&snapshot).Encode(struct{ Version, Count int }{99, 0}))
+	cache := goffeine.NewBuilder[int, int]().MaximumSize(100).Build()
+	assert.ErrorIs(t, cache.LoadFrom(&snapshot, goffeine.JSONCodec[int, int]{}), goffeine.ErrSnapshotVersion)
+
+	// version 1 had no SavedAt
+	snapshot.Reset()
+	assert.Nil(t, gob.NewEncoder(&snapshot).Encode(struct{ Version, Count int }{1, 0}))
+	assert.ErrorIs(t, cache.LoadFrom(&snapshot, goffeine.JSONCodec[int, int]{}), goffeine.ErrSnapshotVersion)
+
+	assert.NotNil(t, cache.LoadFrom(bytes.NewReader([]byte("not a snapshot")), goffeine.JSONCodec[int, int]{}))
+}
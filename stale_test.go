@@ -0,0 +1,82 @@
+package goffeine_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServeStaleWhileRevalidating(t *testing.T) {
+	ticker := &FakeTicker{}
+	release := make(chan struct{})
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Second, 1).
+		ServeStale(10 * time.Second).Ticker(ticker).
+		CacheLoader(goffeine.LoaderFunc[string, int](func(key string) (int, error) {
+			<-release
+			return int(loads.Add(1)) + 1, nil
+		})).Build()
+
+	cache.Put("key", 1)
+	v, stale, ok := cache.GetWithStale("key")
+	assert.Equal(t, []any{1, false, true}, []any{v, stale, ok})
+
+	ticker.Advance(2 * time.Second)
+	for i := 0; i < 3; i++ {
+		v, stale, ok = cache.GetWithStale("key")
+		assert.Equal(t, []any{1, true, true}, []any{v, stale, ok})
+	}
+	v, ok = cache.Get("key")
+	assert.Equal(t, []any{1, true}, []any{v, ok})
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		v, stale, _ := cache.GetWithStale("key")
+		return v == 2 && !stale
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), loads.Load(), "the stale reads share a single reload")
+}
+
+func TestServeStaleIfErrorUntilTheGracePeriodEnds(t *testing.T) {
+	ticker := &FakeTicker{}
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Second, 1).
+		ServeStale(10 * time.Second).Ticker(ticker).BuildLoading(failingLoader(&loads, errUnavailable))
+
+	cache.Put("key", 1)
+	ticker.Advance(2 * time.Second)
+	v, stale, err := cache.GetWithStale("key")
+	assert.Nil(t, err)
+	assert.Equal(t, []any{1, true}, []any{v, stale})
+	assert.Eventually(t, func() bool { return loads.Load() > 0 }, time.Second, time.Millisecond)
+
+	ticker.Advance(8 * time.Second)
+	cache.CleanUp()
+	v, err = cache.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 1, cache.EstimatedSize())
+
+	// once the grace period is over, the entry is gone and Get loads it
+	ticker.Advance(time.Second)
+	cache.CleanUp()
+	assert.Equal(t, 0, cache.EstimatedSize())
+	_, err = cache.Get("key")
+	assert.ErrorIs(t, err, errUnavailable)
+}
+
+func TestServeStaleDoesNotKeepCachedFailures(t *testing.T) {
+	ticker := &FakeTicker{}
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ServeStale(time.Minute).
+		CacheLoadFailures(time.Second).Ticker(ticker).BuildLoading(failingLoader(&loads, errUnavailable))
+
+	cache.Get("key")
+	ticker.Advance(2 * time.Second)
+	cache.CleanUp()
+	assert.Equal(t, 0, cache.EstimatedSize())
+	cache.Get("key")
+	assert.Equal(t, int32(2), loads.Load())
+}
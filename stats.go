@@ -0,0 +1,158 @@
+package goffeine
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats is an immutable snapshot of the statistics of a cache, returned by Goffeine.Stats.
+type CacheStats struct {
+	hitCount         uint64
+	missCount        uint64
+	negativeHitCount uint64
+	loadSuccessCount uint64
+	loadFailureCount uint64
+	totalLoadTime    time.Duration
+	evictionCount    uint64
+	evictionWeight   uint64
+}
+
+// HitCount returns the number of times a lookup found its entry.
+func (s CacheStats) HitCount() uint64 { return s.hitCount }
+
+// MissCount returns the number of times a lookup did not find its entry, or found it expired.
+func (s CacheStats) MissCount() uint64 { return s.missCount }
+
+// NegativeHitCount returns the number of times a lookup found a cached failure or not found
+// result of the loader, instead of a value. They are neither hits nor misses.
+func (s CacheStats) NegativeHitCount() uint64 { return s.negativeHitCount }
+
+// RequestCount returns the number of lookups, hits, misses and negative hits together.
+func (s CacheStats) RequestCount() uint64 { return s.hitCount + s.missCount + s.negativeHitCount }
+
+// HitRate returns the ratio of lookups that were hits, 1.0 when there were none.
+func (s CacheStats) HitRate() float64 {
+	if s.RequestCount() == 0 {
+		return 1.0
+	}
+	return float64(s.hitCount) / float64(s.RequestCount())
+}
+
+// MissRate returns the ratio of lookups that were misses, 0.0 when there were none.
+func (s CacheStats) MissRate() float64 {
+	if s.RequestCount() == 0 {
+		return 0.0
+	}
+	return float64(s.missCount) / float64(s.RequestCount())
+}
+
+// LoadSuccessCount returns the number of loads and reloads that returned a value.
+func (s CacheStats) LoadSuccessCount() uint64 { return s.loadSuccessCount }
+
+// LoadFailureCount returns the number of loads and reloads that returned an error.
+func (s CacheStats) LoadFailureCount() uint64 { return s.loadFailureCount }
+
+// LoadCount returns the number of loads and reloads, successful or not.
+func (s CacheStats) LoadCount() uint64 { return s.loadSuccessCount + s.loadFailureCount }
+
+// TotalLoadTime returns the time spent loading and reloading values.
+func (s CacheStats) TotalLoadTime() time.Duration { return s.totalLoadTime }
+
+// AverageLoadPenalty returns the average time spent loading a value.
+func (s CacheStats) AverageLoadPenalty() time.Duration {
+	if s.LoadCount() == 0 {
+		return 0
+	}
+	return s.totalLoadTime / time.Duration(s.LoadCount())
+}
+
+// EvictionCount returns the number of entries that were evicted because of size or expiration.
+func (s CacheStats) EvictionCount() uint64 { return s.evictionCount }
+
+// EvictionWeight returns the total weight of the evicted entries.
+func (s CacheStats) EvictionWeight() uint64 { return s.evictionWeight }
+
+func (s CacheStats) String() string {
+	return fmt.Sprintf("CacheStats{hitCount=%d, missCount=%d, negativeHitCount=%d, loadSuccessCount=%d, "+
+		"loadFailureCount=%d, totalLoadTime=%s, evictionCount=%d, evictionWeight=%d}", s.hitCount, s.missCount,
+		s.negativeHitCount, s.loadSuccessCount, s.loadFailureCount, s.totalLoadTime, s.evictionCount,
+		s.evictionWeight)
+}
+
+// statsCounter accumulates the statistics with atomic counters, so recording a hit does not take a
+// lock. A nil statsCounter records nothing, which is how a cache without RecordStats runs.
+type statsCounter struct {
+	hitCount         atomic.Uint64
+	missCount        atomic.Uint64
+	negativeHitCount atomic.Uint64
+	loadSuccessCount atomic.Uint64
+	loadFailureCount atomic.Uint64
+	totalLoadTime    atomic.Int64
+	evictionCount    atomic.Uint64
+	evictionWeight   atomic.Uint64
+}
+
+func (s *statsCounter) recordHits(count int) {
+	if s != nil {
+		s.hitCount.Add(uint64(count))
+	}
+}
+
+func (s *statsCounter) recordMisses(count int) {
+	if s != nil {
+		s.missCount.Add(uint64(count))
+	}
+}
+
+func (s *statsCounter) recordNegativeHits(count int) {
+	if s != nil {
+		s.negativeHitCount.Add(uint64(count))
+	}
+}
+
+func (s *statsCounter) recordLoadSuccess(loadTime time.Duration) {
+	if s != nil {
+		s.loadSuccessCount.Add(1)
+		s.totalLoadTime.Add(int64(loadTime))
+	}
+}
+
+func (s *statsCounter) recordLoadFailure(loadTime time.Duration) {
+	if s != nil {
+		s.loadFailureCount.Add(1)
+		s.totalLoadTime.Add(int64(loadTime))
+	}
+}
+
+// recordLoad records the outcome of a load that took loadTime.
+func (s *statsCounter) recordLoad(err error, loadTime time.Duration) {
+	if err == nil {
+		s.recordLoadSuccess(loadTime)
+	} else {
+		s.recordLoadFailure(loadTime)
+	}
+}
+
+func (s *statsCounter) recordEviction(weight int) {
+	if s != nil {
+		s.evictionCount.Add(1)
+		s.evictionWeight.Add(uint64(weight))
+	}
+}
+
+func (s *statsCounter) snapshot() CacheStats {
+	if s == nil {
+		return CacheStats{}
+	}
+	return CacheStats{
+		hitCount:         s.hitCount.Load(),
+		missCount:        s.missCount.Load(),
+		negativeHitCount: s.negativeHitCount.Load(),
+		loadSuccessCount: s.loadSuccessCount.Load(),
+		loadFailureCount: s.loadFailureCount.Load(),
+		totalLoadTime:    time.Duration(s.totalLoadTime.Load()),
+		evictionCount:    s.evictionCount.Load(),
+		evictionWeight:   s.evictionWeight.Load(),
+	}
+}
@@ -0,0 +1,109 @@
+package goffeine_test
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatsAreZeroWithoutRecordStats(t *testing.T) {
+	cache := NewCacheWithMaximumSize(100)
+	cache.Put("a", 1)
+	cache.Get("a")
+	cache.Get("b")
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(0), stats.RequestCount())
+	assert.Equal(t, 1.0, stats.HitRate())
+	assert.Equal(t, 0.0, stats.MissRate())
+}
+
+func TestStatsHitsAndMisses(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Second, 1).
+		Ticker(ticker).RecordStats().Build()
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("c")
+	cache.GetAll([]string{"a", "b", "c", "d"})
+	ticker.Advance(time.Second)
+	cache.Get("a") // expired
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(4), stats.HitCount())
+	assert.Equal(t, uint64(4), stats.MissCount())
+	assert.Equal(t, uint64(8), stats.RequestCount())
+	assert.Equal(t, 0.5, stats.HitRate())
+	assert.Equal(t, 0.5, stats.MissRate())
+}
+
+func TestStatsEvictions(t *testing.T) {
+	ticker := &FakeTicker{}
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).ExpireAfterWrite(time.Second, 1).
+		Ticker(ticker).RecordStats().Build()
+	for i := 0; i < 150; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+	cache.Invalidate("149") // explicit removals are not evictions
+	stats := cache.Stats()
+	assert.Equal(t, uint64(50), stats.EvictionCount())
+	assert.Equal(t, uint64(50), stats.EvictionWeight())
+
+	ticker.Advance(2 * time.Second)
+	cache.CleanUp()
+	stats = cache.Stats()
+	assert.Equal(t, uint64(149), stats.EvictionCount())
+}
+
+func TestStatsLoads(t *testing.T) {
+	ticker := &FakeTicker{}
+	var lock sync.Mutex
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).Ticker(ticker).RecordStats().BuildLoading(
+		goffeine.LoaderFunc[string, int](func(key string) (int, error) {
+			lock.Lock()
+			ticker.Advance(10 * time.Millisecond)
+			lock.Unlock()
+			if key == "bad" {
+				return 0, errors.New("not found")
+			}
+			return len(key), nil
+		}))
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("bad")
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(1), stats.HitCount())
+	assert.Equal(t, uint64(2), stats.MissCount())
+	assert.Equal(t, uint64(1), stats.LoadSuccessCount())
+	assert.Equal(t, uint64(1), stats.LoadFailureCount())
+	assert.Equal(t, 20*time.Millisecond, stats.TotalLoadTime())
+	assert.Equal(t, 10*time.Millisecond, stats.AverageLoadPenalty())
+}
+
+func TestStatsLoadingGetAllWithoutBulkLoader(t *testing.T) {
+	var loads atomic.Int32
+	cache := goffeine.NewBuilder[string, int]().MaximumSize(100).RecordStats().BuildLoading(
+		goffeine.LoaderFunc[string, int](func(key string) (int, error) {
+			loads.Add(1)
+			return len(key), nil
+		}))
+	cache.Put("a", 1)
+
+	entries, err := cache.GetAll([]string{"a", "bb", "ccc", "bb"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"a": 1, "bb": 2, "ccc": 3}, entries)
+	assert.Equal(t, int32(2), loads.Load())
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(1), stats.HitCount())
+	assert.Equal(t, uint64(2), stats.MissCount())
+	assert.Equal(t, uint64(2), stats.LoadSuccessCount())
+}
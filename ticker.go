@@ -0,0 +1,17 @@
+package goffeine
+
+import "time"
+
+// A Ticker is a time source that returns the number of nanoseconds elapsed since a fixed but
+// arbitrary point in time. It lets tests control the clock used for expiration.
+type Ticker interface {
+	Read() int64
+}
+
+// processStart is the fixed point SystemTicker counts from.
+var processStart = time.Now()
+
+// SystemTicker reads the monotonic clock, so the deadlines do not move when the wall clock is set.
+type SystemTicker struct{}
+
+func (SystemTicker) Read() int64 { return int64(time.Since(processStart)) }
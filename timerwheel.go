@@ -0,0 +1,150 @@
+package goffeine
+
+import (
+	"container/list"
+	"goffeine/internal/node"
+	"goffeine/internal/utils"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// timerWheel migrate based on
+// https://github.com/ben-manes/caffeine/blob/master/caffeine/src/main/java/com/github/benmanes/caffeine/cache/TimerWheel.java
+// A hierarchical timer wheel to add, remove, and fire expiration events in amortized O(1) time.
+// The expiration events are deferred until the timer is advanced, which is performed as part of
+// the cache's maintenance cycle.
+//
+// [1] Hashed and Hierarchical Timing Wheels
+// http://www.cs.columbia.edu/~nahum/w6998/papers/ton97-timing-wheels.pdf
+// [2] Apache Kafka's timing wheels
+// https://www.confluent.io/blog/apache-kafka-purgatory-hierarchical-timing-wheels
+var (
+	wheelBuckets = [...]int{64, 64, 32, 4, 1}
+	wheelSpans   = [...]int64{
+		utils.CeilingPowerOfTwo64(time.Second.Nanoseconds()),                               // 1.07s
+		utils.CeilingPowerOfTwo64(time.Minute.Nanoseconds()),                               // 1.14m
+		utils.CeilingPowerOfTwo64(time.Hour.Nanoseconds()),                                 // 1.22h
+		utils.CeilingPowerOfTwo64((24 * time.Hour).Nanoseconds()),                          // 1.63d
+		int64(wheelBuckets[3]) * utils.CeilingPowerOfTwo64((24 * time.Hour).Nanoseconds()), // 6.5d
+		int64(wheelBuckets[3]) * utils.CeilingPowerOfTwo64((24 * time.Hour).Nanoseconds()), // 6.5d
+	}
+	wheelShift = [...]int{
+		bits.TrailingZeros64(uint64(wheelSpans[0])),
+		bits.TrailingZeros64(uint64(wheelSpans[1])),
+		bits.TrailingZeros64(uint64(wheelSpans[2])),
+		bits.TrailingZeros64(uint64(wheelSpans[3])),
+		bits.TrailingZeros64(uint64(wheelSpans[4])),
+	}
+)
+
+type timerWheel[K comparable, V any] struct {
+	wheel [][]*list.List
+	nanos int64
+	// grace is how long an expired value is kept, and served stale, before it is evicted.
+	grace int64
+}
+
+func newTimerWheel[K comparable, V any](nanos int64, grace int64) *timerWheel[K, V] {
+	w := &timerWheel[K, V]{wheel: make([][]*list.List, len(wheelBuckets)), nanos: nanos, grace: grace}
+	for i, n := range wheelBuckets {
+		w.wheel[i] = make([]*list.List, n)
+		for j := range w.wheel[i] {
+			w.wheel[i][j] = list.New()
+		}
+	}
+	return w
+}
+
+// advance moves the wheel forward to currentTimeNanos and hands every expired node to the cache
+// to be evicted. Nodes whose deadline is still ahead are rescheduled into a finer bucket.
+func (w *timerWheel[K, V]) advance(g *Goffeine[K, V], currentTimeNanos int64) {
+	previousTimeNanos := w.nanos
+	w.nanos = currentTimeNanos
+
+	// If wrapping then temporarily shift the clock for a positive comparison. We assume that the
+	// advancements never exceed a total running time of math.MaxInt64 nanoseconds (292 years) so
+	// that an overflow only occurs due to using an arbitrary origin time.
+	if previousTimeNanos < 0 && currentTimeNanos > 0 {
+		previousTimeNanos += math.MaxInt64
+		currentTimeNanos += math.MaxInt64
+	}
+
+	for i := 0; i < len(wheelShift); i++ {
+		previousTicks := int64(uint64(previousTimeNanos) >> wheelShift[i])
+		currentTicks := int64(uint64(currentTimeNanos) >> wheelShift[i])
+		delta := currentTicks - previousTicks
+		if delta <= 0 {
+			break
+		}
+		w.expire(g, i, previousTicks, delta)
+	}
+}
+
+// expire evicts the expired nodes in the buckets of wheel index that the clock passed over
+// between previousTicks and previousTicks+delta, and reschedules the ones that are still alive.
+func (w *timerWheel[K, V]) expire(g *Goffeine[K, V], index int, previousTicks int64, delta int64) {
+	timerWheel := w.wheel[index]
+	mask := int64(len(timerWheel) - 1)
+
+	steps := int(utils.Min(delta+1, len(timerWheel)))
+	start := previousTicks & mask
+	end := start + int64(steps)
+	for i := start; i < end; i++ {
+		bucket := timerWheel[i&mask]
+		timerWheel[i&mask] = list.New()
+		for e := bucket.Front(); e != nil; e = e.Next() {
+			gnode := e.Value.(*node.GoffeineNode[K, V])
+			gnode.TimerElement, gnode.TimerBucket = nil, nil
+			if w.deadline(gnode)-w.nanos > 0 || !g.evictExpired(gnode) {
+				w.schedule(gnode)
+			}
+		}
+	}
+}
+
+// schedule links the node into the bucket that fires closest to its expiration time, unlinking it
+// from its previous bucket first.
+func (w *timerWheel[K, V]) schedule(gnode *node.GoffeineNode[K, V]) {
+	w.deschedule(gnode)
+	bucket := w.findBucket(w.deadline(gnode))
+	gnode.TimerElement = bucket.PushBack(gnode)
+	gnode.TimerBucket = bucket
+}
+
+// deadline returns the time at which the node is evicted: when it expires, or a grace period
+// later for a value, which may be served stale meanwhile.
+func (w *timerWheel[K, V]) deadline(gnode *node.GoffeineNode[K, V]) int64 {
+	expireTime := gnode.ExpireTime.Load()
+	if w.grace > 0 && gnode.Err() == nil {
+		return expireTime + w.grace
+	}
+	return expireTime
+}
+
+// isDue reports whether the node's deadline has passed.
+func (w *timerWheel[K, V]) isDue(gnode *node.GoffeineNode[K, V]) bool {
+	return gnode.ExpireTime.Load() != 0 && w.nanos >= w.deadline(gnode)
+}
+
+// deschedule removes the node from the wheel if it is scheduled.
+func (w *timerWheel[K, V]) deschedule(gnode *node.GoffeineNode[K, V]) {
+	if gnode.TimerBucket != nil {
+		gnode.TimerBucket.Remove(gnode.TimerElement)
+		gnode.TimerElement, gnode.TimerBucket = nil, nil
+	}
+}
+
+// findBucket determines the bucket that the timer event should be added to.
+func (w *timerWheel[K, V]) findBucket(time int64) *list.List {
+	duration := time - w.nanos
+	length := len(w.wheel) - 1
+	for i := 0; i < length; i++ {
+		if duration < wheelSpans[i+1] {
+			ticks := int64(uint64(time) >> wheelShift[i])
+			index := ticks & int64(len(w.wheel[i])-1)
+			return w.wheel[i][index]
+		}
+	}
+	return w.wheel[length][0]
+}
@@ -0,0 +1,87 @@
+package goffeine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"goffeine/internal/node"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type fakeTicker struct {
+	nanos int64
+}
+
+func (t *fakeTicker) Read() int64 { return t.nanos }
+
+func newExpiringCache(ticker *fakeTicker) *Goffeine[string, int] {
+	return NewBuilder[string, int]().MaximumSize(1000).Ticker(ticker).Build()
+}
+
+func TestFindBucket(t *testing.T) {
+	cache := newExpiringCache(&fakeTicker{})
+	wheel := cache.timerWheel
+	assert.Same(t, wheel.wheel[0][1], wheel.findBucket(wheelSpans[0]))
+	assert.Same(t, wheel.wheel[1][1], wheel.findBucket(wheelSpans[1]))
+	assert.Same(t, wheel.wheel[2][1], wheel.findBucket(wheelSpans[2]))
+	assert.Same(t, wheel.wheel[3][1], wheel.findBucket(wheelSpans[3]))
+	assert.Same(t, wheel.wheel[4][0], wheel.findBucket(wheelSpans[4]))
+	assert.Same(t, wheel.wheel[4][0], wheel.findBucket(10*wheelSpans[4]))
+}
+
+func TestScheduleAndDeschedule(t *testing.T) {
+	cache := newExpiringCache(&fakeTicker{})
+	gnode := node.New("a", 1, node.WindowPosition)
+	gnode.ExpireTime.Store(wheelSpans[1])
+
+	cache.timerWheel.schedule(gnode)
+	assert.Same(t, cache.timerWheel.wheel[1][1], gnode.TimerBucket)
+	assert.Equal(t, 1, gnode.TimerBucket.Len())
+
+	bucket := gnode.TimerBucket
+	cache.timerWheel.deschedule(gnode)
+	assert.Nil(t, gnode.TimerBucket)
+	assert.Equal(t, 0, bucket.Len())
+}
+
+func TestAdvanceCascadesToFinerBuckets(t *testing.T) {
+	ticker := &fakeTicker{}
+	cache := newExpiringCache(ticker)
+	cache.PutWithDelay("a", 1, (90 * time.Minute).Milliseconds())
+	gnode := cache.window.Front().Value.(*node.GoffeineNode[string, int])
+	assert.Same(t, cache.timerWheel.findBucket(gnode.ExpireTime.Load()), gnode.TimerBucket)
+
+	ticker.nanos = (89 * time.Minute).Nanoseconds()
+	cache.CleanUp()
+	_, ok := cache.data.Load("a")
+	assert.True(t, ok)
+	assert.NotNil(t, gnode.TimerBucket)
+
+	ticker.nanos = (91 * time.Minute).Nanoseconds()
+	cache.CleanUp()
+	_, ok = cache.data.Load("a")
+	assert.False(t, ok)
+	assert.Nil(t, gnode.TimerBucket)
+}
+
+func TestMaintenanceReclaimsExpiredEntries(t *testing.T) {
+	ticker := &fakeTicker{}
+	cache := newExpiringCache(ticker)
+	for i := 0; i < 500; i++ {
+		cache.PutWithDelay(strconv.Itoa(i), i, int64(i+1)*time.Second.Milliseconds())
+	}
+
+	ticker.nanos = (250 * time.Second).Nanoseconds()
+	cache.CleanUp()
+	size := cache.window.Len() + cache.probation.Len() + cache.protected.Len()
+	assert.Equal(t, 250, size)
+	_, ok := cache.data.Load("249")
+	assert.False(t, ok)
+	_, ok = cache.data.Load("250")
+	assert.True(t, ok)
+
+	ticker.nanos = time.Hour.Nanoseconds()
+	cache.CleanUp()
+	size = cache.window.Len() + cache.probation.Len() + cache.protected.Len()
+	assert.Equal(t, 0, size)
+}
@@ -0,0 +1,66 @@
+package goffeine_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"goffeine"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newBlobCache(maximumWeight int) *goffeine.Goffeine[string, string] {
+	return goffeine.NewBuilder[string, string]().MaximumWeight(maximumWeight).RecordStats().
+		Weigher(func(key string, value string) uint32 { return uint32(len(value)) }).Build()
+}
+
+func TestMaximumWeight(t *testing.T) {
+	cache := newBlobCache(1000)
+	assert.Equal(t, 1000, cache.MaximumWeight())
+	assert.Equal(t, 10, cache.WindowMaximumSize())
+	assert.Equal(t, 198, cache.ProbationMaximumSize())
+	assert.Equal(t, 792, cache.ProtectedMaximumSize())
+
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), strings.Repeat("x", 10+i%50))
+		assert.LessOrEqual(t, cache.WeightedSize(), 1000)
+	}
+	stats := cache.Stats()
+	assert.Equal(t, uint64(100-cache.EstimatedSize()), stats.EvictionCount())
+	assert.Greater(t, stats.EvictionWeight(), stats.EvictionCount())
+}
+
+func TestEntryHeavierThanTheMaximumIsEvicted(t *testing.T) {
+	cache := newBlobCache(100)
+	cache.Put("small", strings.Repeat("x", 10))
+	cache.Put("huge", strings.Repeat("x", 101))
+
+	_, ok := cache.Get("huge")
+	assert.False(t, ok)
+	_, ok = cache.Get("small")
+	assert.True(t, ok)
+	assert.Equal(t, 10, cache.WeightedSize())
+}
+
+func TestUpdateChangesTheWeight(t *testing.T) {
+	cache := newBlobCache(100)
+	cache.Put("a", strings.Repeat("x", 10))
+	cache.Put("b", strings.Repeat("x", 20))
+	assert.Equal(t, 30, cache.WeightedSize())
+
+	cache.Put("a", strings.Repeat("x", 50))
+	assert.Equal(t, 70, cache.WeightedSize())
+
+	cache.Put("b", strings.Repeat("x", 60)) // 110 > 100
+	assert.LessOrEqual(t, cache.WeightedSize(), 100)
+	assert.Equal(t, 1, cache.EstimatedSize())
+}
+
+func TestZeroWeightIsNeverEvictedForSize(t *testing.T) {
+	cache := newBlobCache(50)
+	cache.Put("pinned", "")
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), strings.Repeat("x", 10))
+	}
+	_, ok := cache.Get("pinned")
+	assert.True(t, ok)
+}